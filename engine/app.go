@@ -40,6 +40,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/go-redis/redis/v8"
@@ -111,6 +112,10 @@ type AppIns struct {
 	nsq     *NsqClient
 	nats    *nats.Conn
 
+	registry   ServiceRegistry
+	registered *ServiceInstance
+	heartbeat  chan struct{}
+
 	//mode    int
 	goProcs int
 	running bool
@@ -226,6 +231,7 @@ func (app *AppIns) Startup() {
 	sigQuit := make(chan os.Signal, 1)
 	sigReload := make(chan os.Signal, 1)
 	signal.Notify(sigQuit, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigReload, syscall.SIGHUP)
 	/*
 		// TODO : POSIX only. Windows does not support 'SIGSUR2'
 		signal.Notify(sigReload, syscall.SIGUSR2)
@@ -240,9 +246,10 @@ func (app *AppIns) Startup() {
 				fmt.Println()
 				app.Shutdown()
 			case <-sigReload:
-				// Reload (configurations)
+				// Reload (configurations & certificates)
 				fmt.Println()
 				app.ReloadConfig()
+				ReloadTLS()
 			}
 		}
 	}()
@@ -293,12 +300,44 @@ func (app *AppIns) Startup() {
 		} else {
 			app.logger.Debugf("NATS subscribed to <%s>", topic)
 		}
+
+		// Cache invalidation broadcast
+		topic = fmt.Sprintf("%s%s", CacheTopicPrefix, _msgTarget(app.Name))
+		_, err = app.nats.Subscribe(topic, _cacheNatsConsumerHandler)
+		if err != nil {
+			app.logger.Error(err)
+		} else {
+			app.logger.Debugf("NATS subscribed to <%s>", topic)
+		}
+
+		if Config().GetBool("rpc.nats.enabled") {
+			// RPC over NATS, one instance of queue group answers
+			topic = fmt.Sprintf("%s%s", RPCTopicPrefix, _msgTarget(app.Name))
+			_, err = app.nats.QueueSubscribe(topic, RPCTopicPrefix, _rpcNatsConsumerHandler)
+			if err != nil {
+				app.logger.Error(err)
+			} else {
+				app.logger.Debugf("NATS subscribed to <%s> in queue group", topic)
+			}
+		}
+	}
+
+	if rpcClientTLS == nil {
+		if conf := TLSConfigFromConfig("rpc.client.tls"); conf != nil {
+			err := SetRPCClientTLS(conf)
+			if err != nil {
+				app.logger.Errorf("RPC client TLS failed : %s", err.Error())
+			}
+		}
 	}
 
 	if app.rpc != nil {
 		// RPC
 		app.rpc.Startup(app.Logger())
 		app.waiter.Add(1)
+		if app.registry != nil && app.rpc.listener != nil {
+			app.registerService()
+		}
 	}
 
 	if app.http != nil {
@@ -332,6 +371,12 @@ func (app *AppIns) Shutdown() {
 		// Close worker
 	}
 
+	if app.registry != nil {
+		// Callers stop routing to this instance first
+		app.deregisterService()
+		resetServiceViews()
+	}
+
 	if app.metrics != nil {
 		app.Logger().Debug("Metric node shutting down ...")
 		app.metrics.Shutdown()
@@ -353,6 +398,8 @@ func (app *AppIns) Shutdown() {
 		app.waiter.Done()
 	}
 
+	CloseRPCClients()
+
 	if app.nats != nil {
 		app.Logger().Debug("NATS disconnecting ...")
 		app.nats.Close()
@@ -396,6 +443,8 @@ func (app *AppIns) LoadConfig() {
 		app.Logger().Error(err)
 	}
 
+	configChanged()
+
 	return
 }
 
@@ -409,6 +458,8 @@ func (app *AppIns) ReloadConfig() {
 		app.Logger().Error(err)
 	}
 
+	configChanged()
+
 	return
 }
 
@@ -427,6 +478,8 @@ func (app *AppIns) SetDefaultConfig(defaults map[string]interface{}) error {
 		}
 	}
 
+	configChanged()
+
 	return nil
 }
 
@@ -445,6 +498,8 @@ func (app *AppIns) SetConfigs(c map[string]interface{}) error {
 		}
 	}
 
+	configChanged()
+
 	return nil
 }
 
@@ -532,6 +587,19 @@ func (app *AppIns) SetNats(nats *nats.Conn) {
 
 /* }}} */
 
+// SetRegistry : Set service registry, RPC server registered into it and receivers resolved through it
+/* {{{ [AppIns::SetRegistry] */
+func (app *AppIns) SetRegistry(registry ServiceRegistry) {
+	app.registry = registry
+	if _defaultRegistryInstance == nil {
+		_defaultRegistryInstance = registry
+	}
+
+	return
+}
+
+/* }}} */
+
 // SetNWorker : Set number of workers
 /* {{{ [AppIns::SetNWorker] */
 func (app *AppIns) SetNWorker(n int) {
@@ -563,6 +631,11 @@ func (app *AppIns) Config() *viper.Viper {
 	return app.config
 }
 
+// HTTP : Get HTTP server
+func (app *AppIns) HTTP() *HTTPServer {
+	return app.http
+}
+
 // Metrics : Get metrics
 func (app *AppIns) Metrics() *MetricsIns {
 	return app.metrics
@@ -588,6 +661,11 @@ func (app *AppIns) Nats() *nats.Conn {
 	return app.nats
 }
 
+// Registry : Get service registry
+func (app *AppIns) Registry() ServiceRegistry {
+	return app.registry
+}
+
 // IsRunning : running status
 func (app *AppIns) IsRunning() bool {
 	return app.running
@@ -606,6 +684,7 @@ var (
 	_defaultRedisInstance    *redis.Client
 	_defaultNsqInstance      *NsqClient
 	_defaultNatsInstance     *nats.Conn
+	_defaultRegistryInstance ServiceRegistry
 )
 
 // App : Get default app
@@ -657,6 +736,11 @@ func Nats() *nats.Conn {
 	return _defaultNatsInstance
 }
 
+// Registry : Get default service registry
+func Registry() ServiceRegistry {
+	return _defaultRegistryInstance
+}
+
 // Debug : Get debug status of default app instance
 func Debug() bool {
 	return _defaultAppInstance.Debug
@@ -692,6 +776,39 @@ func ensureDefaultConfig(appName string, enableBranch bool) *viper.Viper {
 	return _defaultConfigInstance
 }
 
+// configGeneration : Bumped when configuration loaded or set
+var configGeneration uint64
+
+func configChanged() {
+	atomic.AddUint64(&configGeneration, 1)
+
+	return
+}
+
+// configCache : Value parsed from configuration once, parsed again after configuration changed
+type configCache struct {
+	lock  sync.Mutex
+	gen   uint64
+	value interface{}
+	parse func() interface{}
+}
+
+func newConfigCache(parse func() interface{}) *configCache {
+	return &configCache{parse: parse}
+}
+
+func (c *configCache) get() interface{} {
+	gen := atomic.LoadUint64(&configGeneration)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.value == nil || c.gen != gen {
+		c.value = c.parse()
+		c.gen = gen
+	}
+
+	return c.value
+}
+
 // EnableBranch : Set enableBranch globally
 func EnableBranch() bool {
 	enableBranch = true
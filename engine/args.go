@@ -0,0 +1,740 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file args.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	httpArgsKey = "_engine.http.args"
+)
+
+// HTTPArgs : Request arguments, body parsed once per request
+type HTTPArgs struct {
+	ctx     *fasthttp.RequestCtx
+	body    map[string]interface{}
+	bodyErr error
+	parsed  bool
+}
+
+// HTTPArgsOf : Get arguments accessor of request, cached on context
+/* {{{ [HTTPArgsOf] */
+func HTTPArgsOf(ctx *fasthttp.RequestCtx) *HTTPArgs {
+	args, ok := ctx.UserValue(httpArgsKey).(*HTTPArgs)
+	if !ok {
+		args = &HTTPArgs{ctx: ctx}
+		ctx.SetUserValue(httpArgsKey, args)
+	}
+
+	return args
+}
+
+/* }}} */
+
+// parse : Decode request body by content-type
+/* {{{ [HTTPArgs::parse] */
+func (a *HTTPArgs) parse() {
+	if a.parsed {
+		return
+	}
+
+	a.parsed = true
+	a.body = make(map[string]interface{})
+	contentType := strings.ToLower(string(a.ctx.Request.Header.ContentType()))
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		// Body of upload route streamed into files already
+		values, err := httpMultipartValues(a.ctx)
+		if err != nil {
+			a.bodyErr = err
+		}
+
+		for k, vs := range values {
+			a.body[k] = stringsToValue(vs)
+		}
+
+		return
+	}
+
+	body := a.ctx.Request.Body()
+	if len(body) == 0 {
+		return
+	}
+
+	if strings.HasPrefix(contentType, "application/json") {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		a.bodyErr = dec.Decode(&a.body)
+	} else if strings.HasPrefix(contentType, "application/xml") {
+		a.body, a.bodyErr = xmlToMap(body)
+	} else {
+		a.ctx.PostArgs().VisitAll(func(k, v []byte) {
+			key := string(k)
+			if exists, ok := a.body[key]; ok {
+				if list, ok := exists.([]interface{}); ok {
+					a.body[key] = append(list, string(v))
+				} else {
+					a.body[key] = []interface{}{exists, string(v)}
+				}
+			} else {
+				a.body[key] = string(v)
+			}
+		})
+	}
+
+	if a.bodyErr != nil {
+		Logger().Debugf("HTTP arguments : Parse request body failed : %s", a.bodyErr.Error())
+	}
+
+	return
+}
+
+/* }}} */
+
+// Err : Error occurred while parsing body
+/* {{{ [HTTPArgs::Err] */
+func (a *HTTPArgs) Err() error {
+	a.parse()
+
+	return a.bodyErr
+}
+
+/* }}} */
+
+// Value : Raw value by given key, from path parameters, query string then body. Dot separated key is looked up as nested path in body
+/* {{{ [HTTPArgs::Value] */
+func (a *HTTPArgs) Value(key string) (interface{}, bool) {
+	// UserValue
+	if uv, ok := a.ctx.UserValue(key).(string); ok {
+		return uv, true
+	}
+
+	// QueryString
+	qs := a.ctx.QueryArgs().PeekMulti(key)
+	if len(qs) == 1 {
+		return string(qs[0]), true
+	} else if len(qs) > 1 {
+		list := make([]interface{}, len(qs))
+		for i, q := range qs {
+			list[i] = string(q)
+		}
+
+		return list, true
+	}
+
+	// Body
+	a.parse()
+	if v, ok := a.body[key]; ok {
+		return v, true
+	}
+
+	if !strings.Contains(key, ".") {
+		return nil, false
+	}
+
+	var cur interface{} = a.body
+	for _, part := range strings.Split(key, ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+
+			cur = v
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+/* }}} */
+
+// Has : Argument exists
+func (a *HTTPArgs) Has(key string) bool {
+	_, ok := a.Value(key)
+
+	return ok
+}
+
+/* {{{ [HTTPArgs::Getters] */
+
+// StringE : Get string value, error if missing
+func (a *HTTPArgs) StringE(key string) (string, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return "", argMissingError(key)
+	}
+
+	s, err := toString(v)
+	if err != nil {
+		return "", argInvalidError(key, err)
+	}
+
+	return s, nil
+}
+
+// String : Get string value, default value returned if missing or invalid
+func (a *HTTPArgs) String(key string, def ...string) string {
+	s, err := a.StringE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return s
+}
+
+// IntE : Get int value, error if missing or invalid
+func (a *HTTPArgs) IntE(key string) (int64, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return 0, argMissingError(key)
+	}
+
+	i, err := toInt64(v)
+	if err != nil {
+		return 0, argInvalidError(key, err)
+	}
+
+	return i, nil
+}
+
+// Int : Get int value, default value returned if missing or invalid
+func (a *HTTPArgs) Int(key string, def ...int64) int64 {
+	i, err := a.IntE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return i
+}
+
+// UintE : Get unsigned int value, error if missing or invalid
+func (a *HTTPArgs) UintE(key string) (uint64, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return 0, argMissingError(key)
+	}
+
+	u, err := toUint64(v)
+	if err != nil {
+		return 0, argInvalidError(key, err)
+	}
+
+	return u, nil
+}
+
+// Uint : Get unsigned int value, default value returned if missing or invalid
+func (a *HTTPArgs) Uint(key string, def ...uint64) uint64 {
+	u, err := a.UintE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return u
+}
+
+// FloatE : Get float value, error if missing or invalid
+func (a *HTTPArgs) FloatE(key string) (float64, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return 0, argMissingError(key)
+	}
+
+	f, err := toFloat64(v)
+	if err != nil {
+		return 0, argInvalidError(key, err)
+	}
+
+	return f, nil
+}
+
+// Float : Get float value, default value returned if missing or invalid
+func (a *HTTPArgs) Float(key string, def ...float64) float64 {
+	f, err := a.FloatE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return f
+}
+
+// BoolE : Get bool value, error if missing or invalid
+func (a *HTTPArgs) BoolE(key string) (bool, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return false, argMissingError(key)
+	}
+
+	b, err := toBool(v)
+	if err != nil {
+		return false, argInvalidError(key, err)
+	}
+
+	return b, nil
+}
+
+// Bool : Get bool value, default value returned if missing or invalid
+func (a *HTTPArgs) Bool(key string, def ...bool) bool {
+	b, err := a.BoolE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return b
+}
+
+// TimeE : Get time value (RFC3339 or unix timestamp), error if missing or invalid
+func (a *HTTPArgs) TimeE(key string) (time.Time, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return time.Time{}, argMissingError(key)
+	}
+
+	if i, err := toInt64(v); err == nil {
+		return time.Unix(i, 0), nil
+	}
+
+	s, err := toString(v)
+	if err == nil {
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, argInvalidError(key, err)
+}
+
+// Time : Get time value, default value returned if missing or invalid
+func (a *HTTPArgs) Time(key string, def ...time.Time) time.Time {
+	t, err := a.TimeE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return t
+}
+
+// DurationE : Get duration value ("1m30s" or seconds), error if missing or invalid
+func (a *HTTPArgs) DurationE(key string) (time.Duration, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return 0, argMissingError(key)
+	}
+
+	if f, err := toFloat64(v); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+
+	s, err := toString(v)
+	if err == nil {
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		if err == nil {
+			return d, nil
+		}
+	}
+
+	return 0, argInvalidError(key, err)
+}
+
+// Duration : Get duration value, default value returned if missing or invalid
+func (a *HTTPArgs) Duration(key string, def ...time.Duration) time.Duration {
+	d, err := a.DurationE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return d
+}
+
+// StringsE : Get string slice (repeated or comma separated arguments, or array), error if missing or invalid
+func (a *HTTPArgs) StringsE(key string) ([]string, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return nil, argMissingError(key)
+	}
+
+	list := toList(v)
+	ret := make([]string, 0, len(list))
+	for _, item := range list {
+		s, err := toString(item)
+		if err != nil {
+			return nil, argInvalidError(key, err)
+		}
+
+		ret = append(ret, s)
+	}
+
+	return ret, nil
+}
+
+// Strings : Get string slice, default value returned if missing or invalid
+func (a *HTTPArgs) Strings(key string, def ...[]string) []string {
+	l, err := a.StringsE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return l
+}
+
+// IntsE : Get int slice, error if missing or invalid
+func (a *HTTPArgs) IntsE(key string) ([]int64, error) {
+	v, ok := a.Value(key)
+	if !ok {
+		return nil, argMissingError(key)
+	}
+
+	list := toList(v)
+	ret := make([]int64, 0, len(list))
+	for _, item := range list {
+		i, err := toInt64(item)
+		if err != nil {
+			return nil, argInvalidError(key, err)
+		}
+
+		ret = append(ret, i)
+	}
+
+	return ret, nil
+}
+
+// Ints : Get int slice, default value returned if missing or invalid
+func (a *HTTPArgs) Ints(key string, def ...[]int64) []int64 {
+	l, err := a.IntsE(key)
+	if err != nil && len(def) > 0 {
+		return def[0]
+	}
+
+	return l
+}
+
+/* }}} */
+
+// Map : All arguments merged into one map, path parameters override query string, query string overrides body
+/* {{{ [HTTPArgs::Map] */
+func (a *HTTPArgs) Map() map[string]interface{} {
+	a.parse()
+	m := make(map[string]interface{}, len(a.body))
+	for k, v := range a.body {
+		m[k] = normalizeArgValue(v)
+	}
+
+	a.ctx.QueryArgs().VisitAll(func(k, v []byte) {
+		key := string(k)
+		m[key], _ = a.Value(key)
+	})
+
+	a.ctx.VisitUserValues(func(k []byte, v interface{}) {
+		if s, ok := v.(string); ok && !bytes.HasPrefix(k, []byte("_")) {
+			m[string(k)] = s
+		}
+	})
+
+	return m
+}
+
+/* }}} */
+
+/* {{{ [Helpers] */
+
+// normalizeArgValue : Copy of value with json.Number converted into int64 or float64 for other encoders
+func normalizeArgValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := t.Float64()
+
+		return f
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			m[k] = normalizeArgValue(vv)
+		}
+
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, vv := range t {
+			list[i] = normalizeArgValue(vv)
+		}
+
+		return list
+	}
+
+	return v
+}
+
+func argMissingError(key string) error {
+	return Errorf(ErrorCodeBadRequest, http.StatusBadRequest, "Argument <%s> missing", key)
+}
+
+func argInvalidError(key string, err error) error {
+	return Errorf(ErrorCodeBadRequest, http.StatusBadRequest, "Argument <%s> invalid", key).Wrap(err)
+}
+
+func stringsToValue(vs []string) interface{} {
+	if len(vs) == 1 {
+		return vs[0]
+	}
+
+	list := make([]interface{}, len(vs))
+	for i, v := range vs {
+		list[i] = v
+	}
+
+	return list
+}
+
+func toList(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case string:
+		if t == "" {
+			return []interface{}{}
+		}
+
+		parts := strings.Split(t, ",")
+		list := make([]interface{}, len(parts))
+		for i, p := range parts {
+			list[i] = strings.TrimSpace(p)
+		}
+
+		return list
+	}
+
+	return []interface{}{v}
+}
+
+func toString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	}
+
+	return "", fmt.Errorf("Cannot convert %T to string", v)
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(t), 10, 64)
+	case json.Number:
+		i, err := t.Int64()
+		if err == nil {
+			return i, nil
+		}
+
+		f, err := t.Float64()
+		if err == nil && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+
+		return 0, fmt.Errorf("Number %s is not an integer", t)
+	case float64:
+		if t == math.Trunc(t) {
+			return int64(t), nil
+		}
+
+		return 0, fmt.Errorf("Number %v is not an integer", t)
+	}
+
+	return 0, fmt.Errorf("Cannot convert %T to int", v)
+}
+
+func toUint64(v interface{}) (uint64, error) {
+	switch t := v.(type) {
+	case string:
+		return strconv.ParseUint(strings.TrimSpace(t), 10, 64)
+	case json.Number:
+		return strconv.ParseUint(t.String(), 10, 64)
+	}
+
+	i, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+
+	if i < 0 {
+		return 0, fmt.Errorf("Negative value %d", i)
+	}
+
+	return uint64(i), nil
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(t), 64)
+	case json.Number:
+		return t.Float64()
+	case float64:
+		return t, nil
+	}
+
+	return 0, fmt.Errorf("Cannot convert %T to float", v)
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(t))
+	case json.Number:
+		f, err := t.Float64()
+		return f != 0, err
+	case float64:
+		return t != 0, nil
+	}
+
+	return false, fmt.Errorf("Cannot convert %T to bool", v)
+}
+
+// xmlToMap : Decode XML document into map, children of root element as keys
+func xmlToMap(data []byte) (map[string]interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := tok.(xml.StartElement); ok {
+			v, err := xmlElement(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				m = make(map[string]interface{})
+			}
+
+			return m, nil
+		}
+	}
+}
+
+// xmlElement : Decode element, text value or map of children (repeated children as list)
+func xmlElement(dec *xml.Decoder) (interface{}, error) {
+	var (
+		text     strings.Builder
+		children map[string]interface{}
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := xmlElement(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			if children == nil {
+				children = make(map[string]interface{})
+			}
+
+			name := t.Name.Local
+			if exists, ok := children[name]; ok {
+				if list, ok := exists.([]interface{}); ok {
+					children[name] = append(list, v)
+				} else {
+					children[name] = []interface{}{exists, v}
+				}
+			} else {
+				children[name] = v
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
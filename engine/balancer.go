@@ -0,0 +1,508 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file balancer.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Balance policies
+const (
+	BalanceRoundRobin       = "round_robin"
+	BalanceRandom           = "random"
+	BalanceLeastOutstanding = "least_outstanding"
+	BalanceWeighted         = "weighted"
+	BalanceConsistentHash   = "consistent_hash"
+)
+
+// Outlier detection settings, overridden by config "rpc.outlier.consecutive_errors", "base_ejection", "max_ejection" and "max_ejection_percent"
+const (
+	DefaultOutlierConsecutiveErrors  = 5
+	DefaultOutlierBaseEjection       = 30 * time.Second
+	DefaultOutlierMaxEjection        = 5 * time.Minute
+	DefaultOutlierMaxEjectionPercent = 50
+	ConsistentHashReplicas           = 160
+)
+
+// Balancer : Policy picking instance of service, calls of one service serialized
+type Balancer interface {
+	// Pick : One of instances (never empty), key given by caller for sticky routing
+	Pick(instances []*ServiceInstance, key string) *ServiceInstance
+}
+
+// BalancerFactory : Create balancer (with its own state) for one service
+type BalancerFactory func() Balancer
+
+// BalancerStat : Selection statistics of instance
+type BalancerStat struct {
+	ID           string    `json:"id" xml:"id"`
+	Addr         string    `json:"addr" xml:"addr"`
+	Picks        uint64    `json:"picks" xml:"picks"`
+	Failures     uint64    `json:"failures" xml:"failures"`
+	Outstanding  int64     `json:"outstanding" xml:"outstanding"`
+	Ejected      bool      `json:"ejected" xml:"ejected"`
+	EjectedUntil time.Time `json:"ejected_until,omitempty" xml:"ejected_until,omitempty"`
+}
+
+// serviceBalancer : Balancer and instance statistics of service
+type serviceBalancer struct {
+	lock     sync.Mutex
+	balancer Balancer
+	stats    map[string]*instanceStats
+}
+
+type instanceStats struct {
+	addr         string
+	picks        uint64
+	failures     uint64
+	consecutive  int
+	ejections    int
+	ejectedUntil time.Time
+}
+
+var (
+	balancerFactories = map[string]BalancerFactory{
+		BalanceRoundRobin:       func() Balancer { return new(roundRobinBalancer) },
+		BalanceRandom:           func() Balancer { return new(randomBalancer) },
+		BalanceLeastOutstanding: func() Balancer { return new(leastOutstandingBalancer) },
+		BalanceWeighted:         func() Balancer { return new(weightedBalancer) },
+		BalanceConsistentHash:   func() Balancer { return new(consistentHashBalancer) },
+	}
+	balancePolicies = make(map[string]string)
+	balancers       = make(map[string]*serviceBalancer)
+	balancersLock   sync.Mutex
+
+	balancerPicks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_balancer_picks_total",
+		Help: "Calls routed to instance by RPC balancer",
+	}, []string{"service", "instance"})
+	balancerFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_balancer_failures_total",
+		Help: "Transport failures of instance",
+	}, []string{"service", "instance"})
+	balancerEjections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_balancer_ejections_total",
+		Help: "Outlier ejections of instance",
+	}, []string{"service", "instance"})
+)
+
+// RegisterBalancer : Register custom balance policy
+/* {{{ [RegisterBalancer] */
+func RegisterBalancer(policy string, factory BalancerFactory) {
+	balancersLock.Lock()
+	balancerFactories[policy] = factory
+	balancersLock.Unlock()
+
+	return
+}
+
+/* }}} */
+
+// SetBalancePolicy : Balance policy of service, config "rpc.balance.policy" (round robin by default) used if not set
+/* {{{ [SetBalancePolicy] */
+func SetBalancePolicy(service, policy string) {
+	service = _msgTarget(service)
+	balancersLock.Lock()
+	balancePolicies[service] = policy
+	// Created again with new policy
+	delete(balancers, service)
+	balancersLock.Unlock()
+
+	return
+}
+
+/* }}} */
+
+// getServiceBalancer : Balancer of service, created by policy on first use
+func getServiceBalancer(service string) *serviceBalancer {
+	balancersLock.Lock()
+	defer balancersLock.Unlock()
+
+	sb, ok := balancers[service]
+	if ok {
+		return sb
+	}
+
+	policy, ok := balancePolicies[service]
+	if !ok {
+		policy = Config().GetString("rpc.balance.policy")
+	}
+
+	if policy == "" {
+		policy = BalanceRoundRobin
+	}
+
+	factory, ok := balancerFactories[policy]
+	if !ok {
+		Logger().Warnf("Balancer : Unknown policy <%s> of service <%s>, round robin used", policy, service)
+		factory = balancerFactories[BalanceRoundRobin]
+	}
+
+	sb = &serviceBalancer{
+		balancer: factory(),
+		stats:    make(map[string]*instanceStats),
+	}
+	balancers[service] = sb
+
+	return sb
+}
+
+// stat : Statistics of instance, created on first use
+func (sb *serviceBalancer) stat(inst *ServiceInstance) *instanceStats {
+	st, ok := sb.stats[inst.ID]
+	if !ok {
+		st = new(instanceStats)
+		sb.stats[inst.ID] = st
+	}
+
+	st.addr = inst.Address()
+
+	return st
+}
+
+// pickInstance : Pick instance of service by its balancer, ejected outliers and open circuits skipped unless none left
+/* {{{ [pickInstance] */
+func pickInstance(service string, instances []*ServiceInstance, key string) *ServiceInstance {
+	sb := getServiceBalancer(service)
+	now := time.Now()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	if len(sb.stats) > 2*len(instances) {
+		// Forget instances gone
+		alive := make(map[string]bool, len(instances))
+		for _, inst := range instances {
+			alive[inst.ID] = true
+		}
+
+		for id := range sb.stats {
+			if !alive[id] {
+				delete(sb.stats, id)
+			}
+		}
+	}
+
+	candidates := make([]*ServiceInstance, 0, len(instances))
+	for _, inst := range instances {
+		if now.Before(sb.stat(inst).ejectedUntil) || circuitRejecting(service, inst.Address()) {
+			continue
+		}
+
+		candidates = append(candidates, inst)
+	}
+
+	if len(candidates) == 0 {
+		// Panic mode, all instances better than none
+		candidates = instances
+	}
+
+	inst := sb.balancer.Pick(candidates, key)
+	if inst == nil {
+		inst = candidates[0]
+	}
+
+	st := sb.stat(inst)
+	st.picks++
+	balancerPicks.WithLabelValues(service, st.addr).Inc()
+
+	return inst
+}
+
+/* }}} */
+
+// reportInstance : Feed call result of instance into outlier detection
+/* {{{ [reportInstance] */
+func reportInstance(service string, inst *ServiceInstance, failed bool) {
+	sb := getServiceBalancer(service)
+	now := time.Now()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	st := sb.stat(inst)
+	if !failed {
+		st.consecutive = 0
+		st.ejections = 0
+
+		return
+	}
+
+	st.failures++
+	st.consecutive++
+	balancerFailures.WithLabelValues(service, st.addr).Inc()
+
+	threshold := Config().GetInt("rpc.outlier.consecutive_errors")
+	if threshold <= 0 {
+		threshold = DefaultOutlierConsecutiveErrors
+	}
+
+	if st.consecutive < threshold || now.Before(st.ejectedUntil) {
+		return
+	}
+
+	maxPercent := Config().GetInt("rpc.outlier.max_ejection_percent")
+	if maxPercent <= 0 {
+		maxPercent = DefaultOutlierMaxEjectionPercent
+	}
+
+	ejected := 0
+	for _, s := range sb.stats {
+		if now.Before(s.ejectedUntil) {
+			ejected++
+		}
+	}
+
+	if (ejected+1)*100 > maxPercent*len(sb.stats) {
+		// Keep enough instances serving
+		return
+	}
+
+	base := rpcClientDuration("rpc.outlier.base_ejection", DefaultOutlierBaseEjection)
+	max := rpcClientDuration("rpc.outlier.max_ejection", DefaultOutlierMaxEjection)
+	st.ejections++
+	d := base * time.Duration(st.ejections)
+	if d > max {
+		d = max
+	}
+
+	st.ejectedUntil = now.Add(d)
+	st.consecutive = 0
+	balancerEjections.WithLabelValues(service, st.addr).Inc()
+	Logger().Warnf("Balancer : Instance [%s] of <%s> ejected for %s after %d consecutive failures", st.addr, service, d, threshold)
+
+	return
+}
+
+/* }}} */
+
+// BalancerStats : Selection statistics of known instances of service
+/* {{{ [BalancerStats] */
+func BalancerStats(service string) []*BalancerStat {
+	service = _msgTarget(service)
+	balancersLock.Lock()
+	sb, ok := balancers[service]
+	balancersLock.Unlock()
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	sb.lock.Lock()
+	stats := make([]*BalancerStat, 0, len(sb.stats))
+	for id, st := range sb.stats {
+		stat := &BalancerStat{
+			ID:       id,
+			Addr:     st.addr,
+			Picks:    st.picks,
+			Failures: st.failures,
+		}
+
+		if now.Before(st.ejectedUntil) {
+			stat.Ejected = true
+			stat.EjectedUntil = st.ejectedUntil
+		}
+
+		stats = append(stats, stat)
+	}
+
+	sb.lock.Unlock()
+	for _, stat := range stats {
+		stat.Outstanding = rpcOutstanding(stat.Addr)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ID < stats[j].ID
+	})
+
+	return stats
+}
+
+/* }}} */
+
+func instanceWeight(inst *ServiceInstance) int {
+	if inst.Weight <= 0 {
+		return 1
+	}
+
+	return inst.Weight
+}
+
+/* {{{ [Balancers] */
+
+type roundRobinBalancer struct {
+	next int
+}
+
+// Pick : Instances in turn
+func (b *roundRobinBalancer) Pick(instances []*ServiceInstance, key string) *ServiceInstance {
+	inst := instances[b.next%len(instances)]
+	b.next++
+
+	return inst
+}
+
+type randomBalancer struct{}
+
+// Pick : Instance at random
+func (b *randomBalancer) Pick(instances []*ServiceInstance, key string) *ServiceInstance {
+	return instances[rand.Intn(len(instances))]
+}
+
+type leastOutstandingBalancer struct{}
+
+// Pick : Instance with fewest calls in flight, ties broken at random
+func (b *leastOutstandingBalancer) Pick(instances []*ServiceInstance, key string) *ServiceInstance {
+	var (
+		picked *ServiceInstance
+		least  int64
+		ties   int
+	)
+
+	for _, inst := range instances {
+		n := rpcOutstanding(inst.Address())
+		switch {
+		case picked == nil || n < least:
+			picked, least, ties = inst, n, 1
+		case n == least:
+			ties++
+			if rand.Intn(ties) == 0 {
+				picked = inst
+			}
+		}
+	}
+
+	return picked
+}
+
+// weightedBalancer : Smooth weighted round robin
+type weightedBalancer struct {
+	current map[string]int
+}
+
+// Pick : Instances in turn proportional to their weights
+func (b *weightedBalancer) Pick(instances []*ServiceInstance, key string) *ServiceInstance {
+	if b.current == nil || len(b.current) > len(instances) {
+		b.current = make(map[string]int, len(instances))
+	}
+
+	var (
+		picked *ServiceInstance
+		total  int
+	)
+
+	for _, inst := range instances {
+		w := instanceWeight(inst)
+		total += w
+		b.current[inst.ID] += w
+		if picked == nil || b.current[inst.ID] > b.current[picked.ID] {
+			picked = inst
+		}
+	}
+
+	b.current[picked.ID] -= total
+
+	return picked
+}
+
+// consistentHashBalancer : Hash ring with virtual nodes (by weight), rebuilt when instances changed
+type consistentHashBalancer struct {
+	signature string
+	ring      []uint32
+	owners    map[uint32]*ServiceInstance
+}
+
+// Pick : Instance owning key on hash ring, random if no key given
+func (b *consistentHashBalancer) Pick(instances []*ServiceInstance, key string) *ServiceInstance {
+	if key == "" {
+		return instances[rand.Intn(len(instances))]
+	}
+
+	var sig strings.Builder
+	for _, inst := range instances {
+		sig.WriteString(inst.ID)
+		sig.WriteByte('#')
+		sig.WriteString(inst.Address())
+		sig.WriteByte('#')
+		sig.WriteString(strconv.Itoa(instanceWeight(inst)))
+		sig.WriteByte(';')
+	}
+
+	if sig.String() != b.signature {
+		b.signature = sig.String()
+		b.ring = b.ring[:0]
+		b.owners = make(map[uint32]*ServiceInstance)
+		for _, inst := range instances {
+			for i := 0; i < ConsistentHashReplicas*instanceWeight(inst); i++ {
+				h := crc32.ChecksumIEEE([]byte(inst.ID + "#" + strconv.Itoa(i)))
+				if _, ok := b.owners[h]; ok {
+					continue
+				}
+
+				b.owners[h] = inst
+				b.ring = append(b.ring, h)
+			}
+		}
+
+		sort.Slice(b.ring, func(i, j int) bool {
+			return b.ring[i] < b.ring[j]
+		})
+	}
+
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(b.ring), func(i int) bool {
+		return b.ring[i] >= h
+	})
+	if idx == len(b.ring) {
+		idx = 0
+	}
+
+	return b.owners[b.ring[idx]]
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,303 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file batch.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Batch settings
+const (
+	DefaultBatchPath        = "/_batch"
+	DefaultBatchConcurrency = 8
+	DefaultBatchMaxRequests = 50
+)
+
+const (
+	httpTimeoutHookKey = "_engine.http.timeout_hook"
+)
+
+// HTTPBatchRequest : Sub-request of batch
+type HTTPBatchRequest struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// HTTPBatchResponse : Sub-response of batch, body is envelope of sub-request
+type HTTPBatchResponse struct {
+	ID         string      `json:"id,omitempty" xml:"id,omitempty"`
+	HTTPStatus int         `json:"http_status" xml:"http_status"`
+	Body       interface{} `json:"body" xml:"body"`
+}
+
+// EnableBatch : Accept list of sub-requests (POST), run them through routes of server
+/* {{{ [HTTPServer::EnableBatch] */
+func (s *HTTPServer) EnableBatch(path string) *HTTPRoute {
+	if path == "" {
+		path = DefaultBatchPath
+	}
+
+	route := &HTTPRoute{
+		Name:        "Batch",
+		Description: "Batch requests",
+		Method:      "POST",
+		Path:        path,
+	}
+	route.Handler = func(ctx *fasthttp.RequestCtx) {
+		s.batchHandler(ctx, route)
+	}
+	s.SetRoutes(route)
+
+	return route
+}
+
+/* }}} */
+
+// batchHandler : Run sub-requests with bounded parallelism
+/* {{{ [HTTPServer::batchHandler] */
+func (s *HTTPServer) batchHandler(ctx *fasthttp.RequestCtx, route *HTTPRoute) {
+	var reqs []*HTTPBatchRequest
+	body := bytes.TrimSpace(ctx.Request.Body())
+	if len(body) > 0 && body[0] == '{' {
+		var wrapper struct {
+			Requests []*HTTPBatchRequest `json:"requests"`
+		}
+
+		err := json.Unmarshal(body, &wrapper)
+		if err != nil {
+			HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Invalid batch request").Wrap(err))
+
+			return
+		}
+
+		reqs = wrapper.Requests
+	} else if err := json.Unmarshal(body, &reqs); err != nil {
+		HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Invalid batch request").Wrap(err))
+
+		return
+	}
+
+	maxRequests := Config().GetInt("http.batch.max_requests")
+	if maxRequests <= 0 {
+		maxRequests = DefaultBatchMaxRequests
+	}
+
+	if len(reqs) == 0 || len(reqs) > maxRequests {
+		HTTPEnvelopeError(ctx, Errorf(ErrorCodeBadRequest, http.StatusBadRequest, "Batch requires 1 to %d requests", maxRequests))
+
+		return
+	}
+
+	concurrency := Config().GetInt("http.batch.concurrency")
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	e := AcquireHTTPEnvelope()
+	resps := make([]*HTTPBatchResponse, len(reqs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req *HTTPBatchRequest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			resps[i] = s.batchDo(ctx, route, req)
+		}(i, req)
+	}
+
+	wg.Wait()
+	e.Data = resps
+	HTTPEnvelope(ctx, e)
+
+	return
+}
+
+/* }}} */
+
+// batchDo : Run one sub-request, failures isolated into its own response
+/* {{{ [HTTPServer::batchDo] */
+func (s *HTTPServer) batchDo(parent *fasthttp.RequestCtx, route *HTTPRoute, req *HTTPBatchRequest) (resp *HTTPBatchResponse) {
+	resp = &HTTPBatchResponse{ID: req.ID}
+	fail := func(err error) {
+		ee := AsError(err)
+		resp.HTTPStatus = ee.HTTPStatus
+		e := AcquireHTTPEnvelope()
+		e.SetError(ee)
+		e.EndTimestamp = e.StartTimestamp
+		resp.Body = e
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fail(recoverError(r, "HTTP batch"))
+		}
+	}()
+
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = fasthttp.MethodGet
+	}
+
+	if !strings.HasPrefix(req.Path, "/") {
+		fail(Errorf(ErrorCodeBadRequest, http.StatusBadRequest, "Invalid path <%s>", req.Path))
+
+		return
+	}
+
+	if r := s.lookupRoute(method, pathOf(req.Path)); r == route {
+		fail(NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Nested batch not allowed"))
+
+		return
+	}
+
+	sub := new(fasthttp.RequestCtx)
+	subReq := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(subReq)
+
+	// Credentials of batch request apply to every sub-request, permissions checked by routes
+	parent.Request.Header.CopyTo(&subReq.Header)
+	subReq.Header.Del("Content-Length")
+	subReq.Header.Del("Content-Type")
+	subReq.Header.Del(IdempotencyKeyHeader)
+	// Sub-responses in codec of batch envelope, embedded as values instead of raw bytes
+	packed := httpAcceptsMsgpack(parent)
+	if packed {
+		subReq.Header.Set("Accept", "application/msgpack")
+	} else {
+		subReq.Header.Set("Accept", "application/json")
+	}
+
+	subReq.Header.SetMethod(method)
+	subReq.SetRequestURI(req.Path)
+	if len(req.Body) > 0 && string(req.Body) != "null" {
+		var raw string
+		if json.Unmarshal(req.Body, &raw) == nil {
+			// String body sent as is
+			subReq.SetBodyString(raw)
+		} else {
+			subReq.Header.SetContentType("application/json")
+			subReq.SetBody(req.Body)
+		}
+	}
+
+	for k, v := range req.Headers {
+		subReq.Header.Set(k, v)
+	}
+
+	sub.Init(subReq, parent.RemoteAddr(), nil)
+
+	var (
+		timeoutResp *fasthttp.Response
+		mu          sync.Mutex
+	)
+
+	sub.SetUserValue(httpTimeoutHookKey, func(r *fasthttp.Response) {
+		mu.Lock()
+		timeoutResp = r
+		mu.Unlock()
+	})
+	s.router.Handler(sub)
+
+	mu.Lock()
+	r := &sub.Response
+	if timeoutResp != nil {
+		// Handler still running with sub, never touch it again
+		r = timeoutResp
+	}
+	mu.Unlock()
+
+	resp.HTTPStatus = r.StatusCode()
+	body := r.Body()
+	ct := r.Header.ContentType()
+	if packed && bytes.HasPrefix(ct, []byte("application/msgpack")) {
+		var v interface{}
+		if msgpack.Unmarshal(body, &v) == nil {
+			resp.Body = v
+
+			return
+		}
+	}
+
+	if bytes.HasPrefix(ct, []byte("application/json")) && json.Valid(body) {
+		resp.Body = json.RawMessage(append([]byte(nil), body...))
+	} else {
+		resp.Body = string(body)
+	}
+
+	return
+}
+
+/* }}} */
+
+// httpAcceptsMsgpack : Envelope of request encoded as msgpack
+func httpAcceptsMsgpack(ctx *fasthttp.RequestCtx) bool {
+	for _, part := range strings.Split(string(ctx.Request.Header.Peek("Accept")), ",") {
+		switch strings.ToLower(strings.TrimSpace(strings.Split(part, ";")[0])) {
+		case "application/msgpack", "application/x-msgpack":
+			return true
+		}
+	}
+
+	return false
+}
+
+func pathOf(uri string) string {
+	if idx := strings.IndexAny(uri, "?#"); idx >= 0 {
+		return uri[:idx]
+	}
+
+	return uri
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,248 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file breaker.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Circuit states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// Circuit breaker settings
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerOpenTimeout      = 10 * time.Second
+	DefaultBreakerHalfOpenProbes   = 1
+)
+
+// circuitBreaker : Stop calling instance of receiver after consecutive failures, probe it again after open timeout
+type circuitBreaker struct {
+	lock     sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+var (
+	breakers     = make(map[string]*circuitBreaker)
+	breakersLock sync.Mutex
+	// errCircuitOpen : Cause of calls rejected by breaker
+	errCircuitOpen = errors.New("Rejected by circuit breaker")
+)
+
+// breakerKey : Breaker kept per instance address, per target if called over NATS (no address)
+func breakerKey(target, addr string) string {
+	if addr == "" {
+		return target
+	}
+
+	return target + "@" + addr
+}
+
+func getBreaker(target, addr string) *circuitBreaker {
+	key := breakerKey(target, addr)
+	breakersLock.Lock()
+	defer breakersLock.Unlock()
+
+	b, ok := breakers[key]
+	if !ok {
+		b = &circuitBreaker{state: CircuitClosed}
+		breakers[key] = b
+	}
+
+	return b
+}
+
+// circuitRejecting : Circuit of instance open and not ready for probe, skipped by balancer
+func circuitRejecting(target, addr string) bool {
+	return circuitState(breakerKey(target, addr)) == CircuitOpen
+}
+
+// CircuitState : State of circuit breaker of target instance by address, empty address for target called over NATS
+func CircuitState(target, addr string) string {
+	return circuitState(breakerKey(_msgTarget(target), addr))
+}
+
+func circuitState(key string) string {
+	breakersLock.Lock()
+	b, ok := breakers[key]
+	breakersLock.Unlock()
+	if !ok {
+		return CircuitClosed
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= rpcClientDuration("rpc.breaker.open_timeout", DefaultBreakerOpenTimeout) {
+		return CircuitHalfOpen
+	}
+
+	return b.state
+}
+
+// allow : Whether call may go, probe is true if call tests half-open circuit
+/* {{{ [circuitBreaker::allow] */
+func (b *circuitBreaker) allow() (probe bool, ok bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < rpcClientDuration("rpc.breaker.open_timeout", DefaultBreakerOpenTimeout) {
+			return false, false
+		}
+
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		fallthrough
+	case CircuitHalfOpen:
+		max := Config().GetInt("rpc.breaker.half_open_probes")
+		if max <= 0 {
+			max = DefaultBreakerHalfOpenProbes
+		}
+
+		if b.probes >= max {
+			return false, false
+		}
+
+		b.probes++
+
+		return true, true
+	}
+
+	return false, true
+}
+
+/* }}} */
+
+// report : Feed call result, probe closes circuit on success or opens it again on failure
+/* {{{ [circuitBreaker::report] */
+func (b *circuitBreaker) report(probe, failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if probe {
+		if b.state != CircuitHalfOpen {
+			return
+		}
+
+		b.probes--
+		if failed {
+			b.state = CircuitOpen
+			b.openedAt = time.Now()
+		} else {
+			b.state = CircuitClosed
+			b.failures = 0
+		}
+
+		return
+	}
+
+	if b.state != CircuitClosed {
+		// Call started before circuit opened
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+
+		return
+	}
+
+	b.failures++
+	threshold := Config().GetInt("rpc.breaker.failure_threshold")
+	if threshold <= 0 {
+		threshold = DefaultBreakerFailureThreshold
+	}
+
+	if b.failures >= threshold {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+
+	return
+}
+
+/* }}} */
+
+// release : Call abandoned before result, probe slot freed without changing state
+func (b *circuitBreaker) release(probe bool) {
+	if !probe {
+		return
+	}
+
+	b.lock.Lock()
+	if b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+
+	b.lock.Unlock()
+
+	return
+}
+
+// finish : Feed call result, calls cancelled by caller say nothing about instance
+func (b *circuitBreaker) finish(ctx context.Context, probe, failed bool) {
+	if ctx.Err() == context.Canceled {
+		b.release(probe)
+
+		return
+	}
+
+	b.report(probe, failed)
+
+	return
+}
+
+func circuitOpenError(target string) error {
+	return Errorf(ErrorCodeUnavailable, http.StatusServiceUnavailable, "Circuit of <%s> open", target).Wrap(errCircuitOpen)
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
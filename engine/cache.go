@@ -0,0 +1,607 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file cache.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	nats "github.com/nats-io/nats.go"
+	"github.com/valyala/fasthttp"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Cache settings
+const (
+	CacheTopicPrefix       = "_.cache_"
+	DefaultHTTPCacheSize   = 1024
+	DefaultHTTPCacheTTL    = 60 * time.Second
+	httpCacheTagsKey       = "_engine.http.cache_tags"
+	httpCacheRedisTagsKeys = "tags:"
+)
+
+// HTTPCacheConfig : Cache settings of GET route, requests with credentials (Authorization / Cookie) not cached unless Private
+type HTTPCacheConfig struct {
+	TTL         time.Duration
+	VaryHeaders []string
+	Tags        []string
+	Store       HTTPCacheStore
+	// Cache credentialed requests per credential
+	Private bool
+}
+
+// HTTPCacheEntry : Cached response
+type HTTPCacheEntry struct {
+	StatusCode   int
+	ContentType  string
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+	Tags         []string
+	// Headers set by handler (Link, Location, custom ...), Set-Cookie excluded
+	Headers map[string][]string
+}
+
+// HTTPCacheStore : Storage of cached responses
+type HTTPCacheStore interface {
+	Get(key string) (*HTTPCacheEntry, bool)
+	Set(key string, entry *HTTPCacheEntry, ttl time.Duration) error
+	InvalidateTags(tags ...string) error
+}
+
+var (
+	_defaultHTTPCacheStore     HTTPCacheStore
+	_defaultHTTPCacheStoreLock sync.Mutex
+	httpCacheRouteStores       []HTTPCacheStore
+	httpCacheRouteStoresLock   sync.Mutex
+)
+
+// httpCacheCredentialHeaders : Headers identifying caller
+var httpCacheCredentialHeaders = []string{"Authorization", "Cookie"}
+
+// httpCacheSkipHeaders : Response headers never stored, per caller or set again on replay
+var httpCacheSkipHeaders = map[string]bool{
+	"Set-Cookie":        true,
+	"Content-Type":      true,
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Date":              true,
+	"Server":            true,
+	"Etag":              true,
+	"Last-Modified":     true,
+	"X-Cache":           true,
+}
+
+// SetHTTPCacheStore : Set default cache store of routes
+func SetHTTPCacheStore(store HTTPCacheStore) {
+	_defaultHTTPCacheStoreLock.Lock()
+	_defaultHTTPCacheStore = store
+	_defaultHTTPCacheStoreLock.Unlock()
+}
+
+// HTTPCacheStoreDefault : Get default cache store, in-memory LRU if not set
+func HTTPCacheStoreDefault() HTTPCacheStore {
+	_defaultHTTPCacheStoreLock.Lock()
+	defer _defaultHTTPCacheStoreLock.Unlock()
+
+	if _defaultHTTPCacheStore == nil {
+		size := DefaultHTTPCacheSize
+		if Config() != nil && Config().GetInt("http.cache.size") > 0 {
+			size = Config().GetInt("http.cache.size")
+		}
+
+		_defaultHTTPCacheStore = NewHTTPCacheLRU(size)
+	}
+
+	return _defaultHTTPCacheStore
+}
+
+/* {{{ [HTTPCacheLRU] */
+
+// HTTPCacheLRU : In-memory LRU cache store
+type HTTPCacheLRU struct {
+	size    int
+	items   map[string]*list.Element
+	order   *list.List
+	tags    map[string]map[string]bool
+	lock    sync.Mutex
+	expires map[string]time.Time
+}
+
+type httpCacheLRUItem struct {
+	key   string
+	entry *HTTPCacheEntry
+}
+
+// NewHTTPCacheLRU : Create in-memory LRU store
+func NewHTTPCacheLRU(size int) *HTTPCacheLRU {
+	if size <= 0 {
+		size = DefaultHTTPCacheSize
+	}
+
+	return &HTTPCacheLRU{
+		size:    size,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+		tags:    make(map[string]map[string]bool),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// Get : Get entry
+func (c *HTTPCacheLRU) Get(key string) (*HTTPCacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(c.expires[key]) {
+		c.remove(el)
+
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+
+	return el.Value.(*httpCacheLRUItem).entry, true
+}
+
+// Set : Set entry
+func (c *HTTPCacheLRU) Set(key string, entry *HTTPCacheEntry, ttl time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	c.items[key] = c.order.PushFront(&httpCacheLRUItem{key: key, entry: entry})
+	c.expires[key] = time.Now().Add(ttl)
+	for _, tag := range entry.Tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]bool)
+		}
+
+		c.tags[tag][key] = true
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+// InvalidateTags : Drop entries with given tags
+func (c *HTTPCacheLRU) InvalidateTags(tags ...string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.items[key]; ok {
+				c.remove(el)
+			}
+		}
+
+		delete(c.tags, tag)
+	}
+
+	return nil
+}
+
+func (c *HTTPCacheLRU) remove(el *list.Element) {
+	item := c.order.Remove(el).(*httpCacheLRUItem)
+	delete(c.items, item.key)
+	delete(c.expires, item.key)
+	for _, tag := range item.entry.Tags {
+		if keys := c.tags[tag]; keys != nil {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+/* }}} */
+
+/* {{{ [HTTPCacheRedis] */
+
+// HTTPCacheRedis : Redis cache store, shared by all replicas
+type HTTPCacheRedis struct {
+	redis  *redis.Client
+	prefix string
+}
+
+// NewHTTPCacheRedis : Create redis store
+func NewHTTPCacheRedis(r *redis.Client, prefix string) *HTTPCacheRedis {
+	if prefix == "" {
+		prefix = fmt.Sprintf("%s.http_cache:", appName)
+	}
+
+	return &HTTPCacheRedis{
+		redis:  r,
+		prefix: prefix,
+	}
+}
+
+// Get : Get entry
+func (c *HTTPCacheRedis) Get(key string) (*HTTPCacheEntry, bool) {
+	raw, err := c.redis.Get(context.Background(), c.prefix+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	entry := new(HTTPCacheEntry)
+	err = msgpack.Unmarshal(raw, entry)
+	if err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Set : Set entry
+func (c *HTTPCacheRedis) Set(key string, entry *HTTPCacheEntry, ttl time.Duration) error {
+	raw, err := msgpack.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pipe := c.redis.TxPipeline()
+	pipe.Set(ctx, c.prefix+key, raw, ttl)
+	for _, tag := range entry.Tags {
+		tk := c.prefix + httpCacheRedisTagsKeys + tag
+		pipe.SAdd(ctx, tk, key)
+		pipe.Expire(ctx, tk, ttl)
+	}
+
+	_, err = pipe.Exec(ctx)
+
+	return err
+}
+
+// InvalidateTags : Drop entries with given tags
+func (c *HTTPCacheRedis) InvalidateTags(tags ...string) error {
+	ctx := context.Background()
+	for _, tag := range tags {
+		tk := c.prefix + httpCacheRedisTagsKeys + tag
+		keys, err := c.redis.SMembers(ctx, tk).Result()
+		if err != nil {
+			return err
+		}
+
+		dels := []string{tk}
+		for _, key := range keys {
+			dels = append(dels, c.prefix+key)
+		}
+
+		err = c.redis.Del(ctx, dels...).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/* }}} */
+
+// HTTPCacheTag : Add cache tags to response of current request
+/* {{{ [HTTPCacheTag] */
+func HTTPCacheTag(ctx *fasthttp.RequestCtx, tags ...string) {
+	exists, _ := ctx.UserValue(httpCacheTagsKey).([]string)
+	ctx.SetUserValue(httpCacheTagsKey, append(exists, tags...))
+}
+
+/* }}} */
+
+// HTTPCacheInvalidate : Drop cached responses with given tags, broadcast to all replicas via NATS
+/* {{{ [HTTPCacheInvalidate] */
+func HTTPCacheInvalidate(tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	err := invalidateHTTPCacheStores(tags...)
+	if err != nil {
+		return err
+	}
+
+	client := App().nats
+	if client == nil {
+		return nil
+	}
+
+	msg := NewUniformMessage(tags, false)
+	msg.Sender = App().Name
+	msg.Reciever = _msgTarget(App().Name)
+	payload, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+
+	return client.Publish(CacheTopicPrefix+msg.Reciever, payload)
+}
+
+/* }}} */
+
+// _cacheNatsConsumerHandler : Invalidation broadcast from replicas
+func _cacheNatsConsumerHandler(m *nats.Msg) {
+	var tags []string
+	msg := NewUniformMessage(nil, false)
+	err := msg.Decode(m.Data)
+	if err == nil {
+		err = msg.verify(TransportNotify)
+	}
+
+	if err == nil {
+		err = msg.Unmarshal(&tags)
+	}
+
+	if err != nil {
+		Logger().Error(err)
+
+		return
+	}
+
+	err = invalidateHTTPCacheStores(tags...)
+	if err != nil {
+		Logger().Error(err)
+	}
+
+	return
+}
+
+// invalidateHTTPCacheStores : Invalidate tags in default store and stores of routes
+func invalidateHTTPCacheStores(tags ...string) error {
+	err := HTTPCacheStoreDefault().InvalidateTags(tags...)
+	httpCacheRouteStoresLock.Lock()
+	stores := append([]HTTPCacheStore(nil), httpCacheRouteStores...)
+	httpCacheRouteStoresLock.Unlock()
+	for _, store := range stores {
+		if e := store.InvalidateTags(tags...); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// mwCache : Serve cached response of GET route, answer conditional requests
+func mwCache(h fasthttp.RequestHandler, conf *HTTPCacheConfig) fasthttp.RequestHandler {
+	ttl := conf.TTL
+	if ttl <= 0 {
+		ttl = DefaultHTTPCacheTTL
+	}
+
+	if conf.Store != nil {
+		httpCacheRouteStoresLock.Lock()
+		httpCacheRouteStores = append(httpCacheRouteStores, conf.Store)
+		httpCacheRouteStoresLock.Unlock()
+	}
+
+	vary := conf.VaryHeaders
+	if conf.Private {
+		vary = append(append([]string{}, vary...), httpCacheCredentialHeaders...)
+	}
+
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		if !ctx.IsGet() && !ctx.IsHead() {
+			h(ctx)
+
+			return
+		}
+
+		if !conf.Private && httpCacheCredentialed(ctx) {
+			// Response of one caller never served to another
+			h(ctx)
+			ctx.Response.Header.Set("X-Cache", "BYPASS")
+
+			return
+		}
+
+		store := conf.Store
+		if store == nil {
+			store = HTTPCacheStoreDefault()
+		}
+
+		key := httpCacheKey(ctx, vary)
+		entry, ok := store.Get(key)
+		if ok {
+			ctx.Response.Header.Set("X-Cache", "HIT")
+			writeCacheEntry(ctx, entry)
+
+			return
+		}
+
+		// Headers set before handler belong to outer middlewares, not cached
+		outer := make(map[string]bool)
+		ctx.Response.Header.VisitAll(func(k, v []byte) {
+			outer[string(k)] = true
+		})
+
+		h(ctx)
+		ctx.Response.Header.Set("X-Cache", "MISS")
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			return
+		}
+
+		sum := sha256.Sum256(ctx.Response.Body())
+		entry = &HTTPCacheEntry{
+			StatusCode:   ctx.Response.StatusCode(),
+			ContentType:  string(ctx.Response.Header.ContentType()),
+			Body:         append([]byte(nil), ctx.Response.Body()...),
+			ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+			LastModified: time.Now().UTC().Truncate(time.Second),
+			Tags:         conf.Tags,
+		}
+		ctx.Response.Header.VisitAll(func(k, v []byte) {
+			hdr := string(k)
+			if outer[hdr] || httpCacheSkipHeaders[hdr] {
+				return
+			}
+
+			if entry.Headers == nil {
+				entry.Headers = make(map[string][]string)
+			}
+
+			entry.Headers[hdr] = append(entry.Headers[hdr], string(v))
+		})
+		if tags, ok := ctx.UserValue(httpCacheTagsKey).([]string); ok {
+			entry.Tags = append(append([]string{}, conf.Tags...), tags...)
+		}
+
+		err := store.Set(key, entry, ttl)
+		if err != nil {
+			Logger().Errorf("HTTP cache set failed : %s", err.Error())
+		}
+
+		writeCacheEntry(ctx, entry)
+
+		return
+	})
+}
+
+// httpCacheKey : Key varies by method, path, sorted query, accept and given headers
+func httpCacheKey(ctx *fasthttp.RequestCtx, vary []string) string {
+	var (
+		query []string
+		b     strings.Builder
+	)
+
+	ctx.QueryArgs().VisitAll(func(k, v []byte) {
+		query = append(query, string(k)+"="+string(v))
+	})
+	sort.Strings(query)
+
+	b.Write(ctx.Method())
+	b.WriteString(" ")
+	b.Write(ctx.Path())
+	b.WriteString("?")
+	b.WriteString(strings.Join(query, "&"))
+	b.WriteString("|")
+	b.Write(ctx.Request.Header.Peek("Accept"))
+	for _, hdr := range vary {
+		b.WriteString("|")
+		b.Write(ctx.Request.Header.Peek(hdr))
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// httpCacheCredentialed : Request carries credential headers
+func httpCacheCredentialed(ctx *fasthttp.RequestCtx) bool {
+	for _, hdr := range httpCacheCredentialHeaders {
+		if len(ctx.Request.Header.Peek(hdr)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeCacheEntry : Respond entry with stored headers, 304 if not modified
+func writeCacheEntry(ctx *fasthttp.RequestCtx, entry *HTTPCacheEntry) {
+	for hdr, values := range entry.Headers {
+		ctx.Response.Header.Del(hdr)
+		for _, v := range values {
+			ctx.Response.Header.Add(hdr, v)
+		}
+	}
+
+	if httpNotModified(ctx, entry) {
+		cache := string(ctx.Response.Header.Peek("X-Cache"))
+		ctx.NotModified()
+		ctx.Response.Header.Set("X-Cache", cache)
+	} else {
+		ctx.SetStatusCode(entry.StatusCode)
+		ctx.SetContentType(entry.ContentType)
+		ctx.SetBody(entry.Body)
+	}
+
+	ctx.Response.Header.Set("ETag", entry.ETag)
+	ctx.Response.Header.Set("Last-Modified", string(fasthttp.AppendHTTPDate(nil, entry.LastModified)))
+
+	return
+}
+
+// httpNotModified : Check If-None-Match / If-Modified-Since
+func httpNotModified(ctx *fasthttp.RequestCtx, entry *HTTPCacheEntry) bool {
+	inm := string(ctx.Request.Header.Peek("If-None-Match"))
+	if inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == entry.ETag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ims := ctx.Request.Header.Peek("If-Modified-Since")
+	if len(ims) > 0 {
+		t, err := fasthttp.ParseHTTPDate(ims)
+		if err == nil && !entry.LastModified.After(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
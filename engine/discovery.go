@@ -0,0 +1,872 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file discovery.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	nats "github.com/nats-io/nats.go"
+	"github.com/spf13/viper"
+)
+
+// Discovery settings
+const (
+	DiscoveryKeyPrefix        = "_.discovery_"
+	DiscoveryProbePrefix      = "_.discovery_probe_"
+	DefaultDiscoveryTTL       = 15 * time.Second
+	DefaultDiscoveryRefresh   = 30 * time.Second
+	DefaultDiscoveryProbeWait = 200 * time.Millisecond
+)
+
+// ServiceInstance : Node of service registered in discovery
+type ServiceInstance struct {
+	ID       string            `json:"id" mapstructure:"id"`
+	Service  string            `json:"service" mapstructure:"service"`
+	Addr     string            `json:"addr" mapstructure:"addr"`
+	Port     int               `json:"port" mapstructure:"port"`
+	Branch   string            `json:"branch,omitempty" mapstructure:"branch"`
+	Version  string            `json:"version,omitempty" mapstructure:"version"`
+	Weight   int               `json:"weight,omitempty" mapstructure:"weight"`
+	Metadata map[string]string `json:"metadata,omitempty" mapstructure:"metadata"`
+	// Announced lifetime, zero means deregistered (NATS)
+	TTL time.Duration `json:"ttl,omitempty" mapstructure:"-"`
+}
+
+// Address : Host and port of RPC server of instance
+func (inst *ServiceInstance) Address() string {
+	port := inst.Port
+	if port == 0 {
+		port = RPCTCPPort
+	}
+
+	return net.JoinHostPort(inst.Addr, strconv.Itoa(port))
+}
+
+// ServiceRegistry : Backend of service discovery
+type ServiceRegistry interface {
+	// Register : Register or refresh instance, expired if not refreshed within ttl
+	Register(ctx context.Context, inst *ServiceInstance, ttl time.Duration) error
+	// Deregister : Remove instance
+	Deregister(ctx context.Context, inst *ServiceInstance) error
+	// Instances : Alive instances of service
+	Instances(ctx context.Context, service string) ([]*ServiceInstance, error)
+	// Watch : Call fn when instances of service changed, until ctx done
+	Watch(ctx context.Context, service string, fn func()) error
+}
+
+/* {{{ [View] */
+
+// serviceView : Locally cached instances of service
+type serviceView struct {
+	instances []*ServiceInstance
+	loadedAt  time.Time
+	cancel    context.CancelFunc
+	// Closed when reload in progress finished, concurrent resolves wait for it
+	loading chan struct{}
+	err     error
+}
+
+var (
+	serviceViews     = make(map[string]*serviceView)
+	serviceViewsLock sync.Mutex
+)
+
+func discoveryRefresh() time.Duration {
+	refresh := Config().GetDuration("discovery.refresh")
+	if refresh <= 0 {
+		refresh = DefaultDiscoveryRefresh
+	}
+
+	return refresh
+}
+
+// resetServiceViews : Drop cached views and stop their watches
+func resetServiceViews() {
+	serviceViewsLock.Lock()
+	for name, view := range serviceViews {
+		if view.cancel != nil {
+			view.cancel()
+		}
+
+		delete(serviceViews, name)
+	}
+
+	serviceViewsLock.Unlock()
+
+	return
+}
+
+// ResolveService : Instances of service from local view, loaded and watched on first use, reloaded when changed
+/* {{{ [ResolveService] */
+func ResolveService(ctx context.Context, service string) ([]*ServiceInstance, error) {
+	registry := Registry()
+	if registry == nil {
+		return nil, fmt.Errorf("No service registry")
+	}
+
+	serviceViewsLock.Lock()
+	view, ok := serviceViews[service]
+	if ok && !view.loadedAt.IsZero() && time.Since(view.loadedAt) < discoveryRefresh() {
+		instances := view.instances
+		serviceViewsLock.Unlock()
+
+		return instances, nil
+	}
+
+	var wctx context.Context
+	if !ok {
+		view = new(serviceView)
+		wctx, view.cancel = context.WithCancel(context.Background())
+		serviceViews[service] = view
+	}
+
+	if view.loading != nil {
+		// Reloaded by another caller
+		loading := view.loading
+		serviceViewsLock.Unlock()
+		select {
+		case <-loading:
+		case <-ctx.Done():
+		}
+
+		serviceViewsLock.Lock()
+		defer serviceViewsLock.Unlock()
+		if len(view.instances) > 0 {
+			return view.instances, nil
+		}
+
+		if view.err != nil {
+			return nil, view.err
+		}
+
+		return nil, ctx.Err()
+	}
+
+	loading := make(chan struct{})
+	view.loading = loading
+	serviceViewsLock.Unlock()
+
+	if wctx != nil {
+		// Watch started out of lock, registry may talk to network
+		err := registry.Watch(wctx, service, func() {
+			// Reload on next resolve
+			serviceViewsLock.Lock()
+			view.loadedAt = time.Time{}
+			serviceViewsLock.Unlock()
+		})
+		if err != nil {
+			Logger().Warnf("Discovery : Watch service <%s> failed : %s", service, err.Error())
+		}
+	}
+
+	instances, err := registry.Instances(ctx, service)
+	serviceViewsLock.Lock()
+	defer serviceViewsLock.Unlock()
+	view.loading = nil
+	view.err = err
+	close(loading)
+	if err != nil {
+		if len(view.instances) > 0 {
+			// Stale view better than nothing
+			Logger().Warnf("Discovery : Load service <%s> failed, stale view used : %s", service, err.Error())
+
+			return view.instances, nil
+		}
+
+		return nil, err
+	}
+
+	// Stable order for balancers, slice of registry untouched
+	instances = append([]*ServiceInstance(nil), instances...)
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+
+	view.instances = instances
+	view.loadedAt = time.Now()
+
+	return instances, nil
+}
+
+/* }}} */
+
+// resolveRPCTarget : Instance picked by balancer for reciever and address of its RPC server, address from config (no instance) if no registry set
+func resolveRPCTarget(ctx context.Context, reciever, key string) (*ServiceInstance, string, error) {
+	if Registry() == nil {
+		return nil, rpcTargetAddr(reciever), nil
+	}
+
+	instances, err := ResolveService(ctx, reciever)
+	if err != nil {
+		return nil, "", NewError(ErrorCodeUnavailable, http.StatusServiceUnavailable, "Service discovery failed").Wrap(err)
+	}
+
+	if len(instances) == 0 {
+		return nil, "", Errorf(ErrorCodeUnavailable, http.StatusServiceUnavailable, "No instance of service <%s> available", reciever)
+	}
+
+	inst := pickInstance(reciever, instances, key)
+
+	return inst, inst.Address(), nil
+}
+
+/* }}} */
+
+/* {{{ [Registration] */
+
+// advertiseAddr : Address published to discovery, bound host or first non-loopback IPv4 address if not configured
+func advertiseAddr(bound string) string {
+	addr := Config().GetString("discovery.advertise_addr")
+	if addr != "" {
+		return addr
+	}
+
+	if host, _, err := net.SplitHostPort(bound); err == nil {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+			// Bound to specific interface
+			return host
+		}
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+				return ipnet.IP.String()
+			}
+		}
+	}
+
+	hostname, _ := os.Hostname()
+
+	return hostname
+}
+
+// registerService : Register RPC server of app into discovery and keep heartbeats
+/* {{{ [AppIns::registerService] */
+func (app *AppIns) registerService() {
+	branch := os.Getenv(BranchEnvName)
+	if branch == "" {
+		branch = DefaultBranchValue
+	}
+
+	id, _ := uuid.NewRandom()
+	inst := &ServiceInstance{
+		ID:       id.String(),
+		Service:  _msgTarget(app.Name),
+		Addr:     advertiseAddr(app.rpc.Addr()),
+		Port:     app.rpc.port(),
+		Branch:   branch,
+		Version:  app.Config().GetString("discovery.version"),
+		Weight:   app.Config().GetInt("discovery.weight"),
+		Metadata: app.Config().GetStringMapString("discovery.metadata"),
+	}
+
+	ttl := app.Config().GetDuration("discovery.ttl")
+	if ttl <= 0 {
+		ttl = DefaultDiscoveryTTL
+	}
+
+	err := app.registry.Register(context.Background(), inst, ttl)
+	if err != nil {
+		app.Logger().Errorf("Discovery : Register <%s> failed : %s", inst.Service, err.Error())
+	} else {
+		app.Logger().Infof("Discovery : Registered <%s> at [%s]", inst.Service, inst.Address())
+	}
+
+	stop := make(chan struct{})
+	app.registered = inst
+	app.heartbeat = stop
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				err := app.registry.Register(context.Background(), inst, ttl)
+				if err != nil {
+					app.Logger().Warnf("Discovery : Heartbeat of <%s> failed : %s", inst.Service, err.Error())
+				}
+			}
+		}
+	}()
+
+	return
+}
+
+/* }}} */
+
+// deregisterService : Stop heartbeats and remove instance from discovery
+/* {{{ [AppIns::deregisterService] */
+func (app *AppIns) deregisterService() {
+	if app.registered == nil {
+		return
+	}
+
+	close(app.heartbeat)
+	err := app.registry.Deregister(context.Background(), app.registered)
+	if err != nil {
+		app.Logger().Errorf("Discovery : Deregister <%s> failed : %s", app.registered.Service, err.Error())
+	} else {
+		app.Logger().Infof("Discovery : Deregistered <%s>", app.registered.Service)
+	}
+
+	app.registered = nil
+
+	return
+}
+
+/* }}} */
+
+/* }}} */
+
+/* {{{ [RedisRegistry] */
+
+// redisRegistry : Instances stored as keys with TTL and indexed by set of service, changes published on service channel
+type redisRegistry struct {
+	client *redis.Client
+}
+
+// NewRedisRegistry : Create service registry on redis
+func NewRedisRegistry(client *redis.Client) ServiceRegistry {
+	return &redisRegistry{client: client}
+}
+
+func redisRegistryKey(service, id string) string {
+	return DiscoveryKeyPrefix + service + ":" + id
+}
+
+// redisRegistrySet : Set of instance IDs of service, expired members removed on read
+func redisRegistrySet(service string) string {
+	return DiscoveryKeyPrefix + service
+}
+
+// Register : Store instance with TTL, announce if newly registered
+func (r *redisRegistry) Register(ctx context.Context, inst *ServiceInstance, ttl time.Duration) error {
+	raw, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+
+	key := redisRegistryKey(inst.Service, inst.ID)
+	created, err := r.client.SetNX(ctx, key, raw, ttl).Result()
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		// Heartbeat
+		err = r.client.Set(ctx, key, raw, ttl).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Re-added by heartbeat if removed as expired meanwhile
+	err = r.client.SAdd(ctx, redisRegistrySet(inst.Service), inst.ID).Err()
+	if err != nil || !created {
+		return err
+	}
+
+	return r.client.Publish(ctx, DiscoveryKeyPrefix+inst.Service, inst.ID).Err()
+}
+
+// Deregister : Delete instance and announce
+func (r *redisRegistry) Deregister(ctx context.Context, inst *ServiceInstance) error {
+	err := r.client.Del(ctx, redisRegistryKey(inst.Service, inst.ID)).Err()
+	if err != nil {
+		return err
+	}
+
+	err = r.client.SRem(ctx, redisRegistrySet(inst.Service), inst.ID).Err()
+	if err != nil {
+		return err
+	}
+
+	return r.client.Publish(ctx, DiscoveryKeyPrefix+inst.Service, inst.ID).Err()
+}
+
+// Instances : Alive instances in set of service
+func (r *redisRegistry) Instances(ctx context.Context, service string) ([]*ServiceInstance, error) {
+	ids, err := r.client.SMembers(ctx, redisRegistrySet(service)).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisRegistryKey(service, id)
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		instances []*ServiceInstance
+		expired   []interface{}
+	)
+
+	for i, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			// Expired without deregister
+			expired = append(expired, ids[i])
+
+			continue
+		}
+
+		inst := new(ServiceInstance)
+		if json.Unmarshal([]byte(raw), inst) == nil {
+			instances = append(instances, inst)
+		}
+	}
+
+	if len(expired) > 0 {
+		r.client.SRem(ctx, redisRegistrySet(service), expired...)
+	}
+
+	return instances, nil
+}
+
+// Watch : Subscribe announcements of service, expired instances caught by refresh of view
+func (r *redisRegistry) Watch(ctx context.Context, service string, fn func()) error {
+	sub := r.client.Subscribe(ctx, DiscoveryKeyPrefix+service)
+	_, err := sub.Receive(ctx)
+	if err != nil {
+		sub.Close()
+
+		return err
+	}
+
+	go func() {
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				fn()
+			}
+		}
+	}()
+
+	return nil
+}
+
+/* }}} */
+
+/* {{{ [NatsRegistry] */
+
+// natsRegistry : Instances announced on service subject, probed by watchers on first use
+type natsRegistry struct {
+	conn       *nats.Conn
+	lock       sync.Mutex
+	services   map[string]*natsService
+	registered map[string]*natsRegistered
+}
+
+type natsService struct {
+	sub       *nats.Subscription
+	instances map[string]*natsInstance
+	watchers  map[int]func()
+	nextID    int
+}
+
+type natsInstance struct {
+	raw     []byte
+	inst    *ServiceInstance
+	expires time.Time
+}
+
+type natsRegistered struct {
+	raw   []byte
+	probe *nats.Subscription
+}
+
+// NewNatsRegistry : Create service registry on NATS
+func NewNatsRegistry(conn *nats.Conn) ServiceRegistry {
+	return &natsRegistry{
+		conn:       conn,
+		services:   make(map[string]*natsService),
+		registered: make(map[string]*natsRegistered),
+	}
+}
+
+// Register : Announce instance, answer probes of watchers
+func (r *natsRegistry) Register(ctx context.Context, inst *ServiceInstance, ttl time.Duration) error {
+	announce := *inst
+	announce.TTL = ttl
+	raw, err := json.Marshal(&announce)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	reg, ok := r.registered[inst.ID]
+	if !ok {
+		reg = new(natsRegistered)
+		r.registered[inst.ID] = reg
+		reg.probe, err = r.conn.Subscribe(DiscoveryProbePrefix+inst.Service, func(*nats.Msg) {
+			r.lock.Lock()
+			raw := reg.raw
+			r.lock.Unlock()
+			r.conn.Publish(DiscoveryKeyPrefix+inst.Service, raw)
+		})
+		if err != nil {
+			delete(r.registered, inst.ID)
+			r.lock.Unlock()
+
+			return err
+		}
+	}
+
+	reg.raw = raw
+	r.lock.Unlock()
+
+	return r.conn.Publish(DiscoveryKeyPrefix+inst.Service, raw)
+}
+
+// Deregister : Announce instance with zero TTL
+func (r *natsRegistry) Deregister(ctx context.Context, inst *ServiceInstance) error {
+	r.lock.Lock()
+	if reg, ok := r.registered[inst.ID]; ok {
+		reg.probe.Unsubscribe()
+		delete(r.registered, inst.ID)
+	}
+
+	r.lock.Unlock()
+
+	announce := *inst
+	announce.TTL = 0
+	raw, err := json.Marshal(&announce)
+	if err != nil {
+		return err
+	}
+
+	err = r.conn.Publish(DiscoveryKeyPrefix+inst.Service, raw)
+	if err != nil {
+		return err
+	}
+
+	return r.conn.Flush()
+}
+
+// track : Subscribe announcements of service, probe registered instances on first use
+func (r *natsRegistry) track(service string) (*natsService, error) {
+	r.lock.Lock()
+	svc, ok := r.services[service]
+	if ok {
+		r.lock.Unlock()
+
+		return svc, nil
+	}
+
+	svc = &natsService{
+		instances: make(map[string]*natsInstance),
+		watchers:  make(map[int]func()),
+	}
+
+	sub, err := r.conn.Subscribe(DiscoveryKeyPrefix+service, func(m *nats.Msg) {
+		r.announced(svc, m.Data)
+	})
+	if err != nil {
+		r.lock.Unlock()
+
+		return nil, err
+	}
+
+	svc.sub = sub
+	r.services[service] = svc
+	r.lock.Unlock()
+
+	err = r.conn.Publish(DiscoveryProbePrefix+service, nil)
+	if err == nil {
+		err = r.conn.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	wait := Config().GetDuration("discovery.nats.probe_wait")
+	if wait <= 0 {
+		wait = DefaultDiscoveryProbeWait
+	}
+
+	time.Sleep(wait)
+
+	return svc, nil
+}
+
+// announced : Update instances by announcement, notify watchers if changed
+func (r *natsRegistry) announced(svc *natsService, raw []byte) {
+	inst := new(ServiceInstance)
+	if json.Unmarshal(raw, inst) != nil || inst.ID == "" {
+		return
+	}
+
+	changed := false
+	r.lock.Lock()
+	old, ok := svc.instances[inst.ID]
+	if inst.TTL <= 0 {
+		if ok {
+			delete(svc.instances, inst.ID)
+			changed = true
+		}
+	} else {
+		changed = !ok || !bytes.Equal(old.raw, raw)
+		svc.instances[inst.ID] = &natsInstance{
+			raw:     raw,
+			inst:    inst,
+			expires: time.Now().Add(inst.TTL),
+		}
+	}
+
+	var watchers []func()
+	if changed {
+		for _, fn := range svc.watchers {
+			watchers = append(watchers, fn)
+		}
+	}
+
+	r.lock.Unlock()
+
+	for _, fn := range watchers {
+		fn()
+	}
+
+	return
+}
+
+// Instances : Announced instances not expired
+func (r *natsRegistry) Instances(ctx context.Context, service string) ([]*ServiceInstance, error) {
+	svc, err := r.track(service)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []*ServiceInstance
+	now := time.Now()
+	r.lock.Lock()
+	for id, ni := range svc.instances {
+		if now.After(ni.expires) {
+			delete(svc.instances, id)
+
+			continue
+		}
+
+		instances = append(instances, ni.inst)
+	}
+
+	r.lock.Unlock()
+
+	return instances, nil
+}
+
+// Watch : Notified by announcements changing instances
+func (r *natsRegistry) Watch(ctx context.Context, service string, fn func()) error {
+	svc, err := r.track(service)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	id := svc.nextID
+	svc.nextID++
+	svc.watchers[id] = fn
+	r.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.lock.Lock()
+		delete(svc.watchers, id)
+		r.lock.Unlock()
+	}()
+
+	return nil
+}
+
+/* }}} */
+
+/* {{{ [StaticRegistry] */
+
+// staticRegistry : Instances listed in file (local development), reloaded when file changed
+type staticRegistry struct {
+	v         *viper.Viper
+	lock      sync.RWMutex
+	instances map[string][]*ServiceInstance
+	watchers  map[int]func()
+	nextID    int
+}
+
+// NewStaticRegistry : Create service registry from file (yaml / json / toml), listing instances as :
+//
+//	instances:
+//	  - service: deuterium.skel.node_dev
+//	    addr: 127.0.0.1
+//	    port: 19080
+func NewStaticRegistry(path string) (ServiceRegistry, error) {
+	r := &staticRegistry{
+		v:        viper.New(),
+		watchers: make(map[int]func()),
+	}
+
+	r.v.SetConfigFile(path)
+	err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	r.v.OnConfigChange(func(fsnotify.Event) {
+		err := r.load()
+		if err != nil {
+			Logger().Errorf("Discovery : Reload static registry failed : %s", err.Error())
+
+			return
+		}
+
+		r.lock.RLock()
+		var watchers []func()
+		for _, fn := range r.watchers {
+			watchers = append(watchers, fn)
+		}
+
+		r.lock.RUnlock()
+		for _, fn := range watchers {
+			fn()
+		}
+	})
+	r.v.WatchConfig()
+
+	return r, nil
+}
+
+func (r *staticRegistry) load() error {
+	err := r.v.ReadInConfig()
+	if err != nil {
+		return err
+	}
+
+	var list []*ServiceInstance
+	err = r.v.UnmarshalKey("instances", &list)
+	if err != nil {
+		return err
+	}
+
+	instances := make(map[string][]*ServiceInstance)
+	for i, inst := range list {
+		if inst.Service == "" || inst.Addr == "" {
+			return fmt.Errorf("Service and addr required by instance %d of static registry", i)
+		}
+
+		if inst.ID == "" {
+			inst.ID = fmt.Sprintf("%s#%d", inst.Service, i)
+		}
+
+		instances[inst.Service] = append(instances[inst.Service], inst)
+	}
+
+	r.lock.Lock()
+	r.instances = instances
+	r.lock.Unlock()
+
+	return nil
+}
+
+// Register : Static registry is read-only
+func (r *staticRegistry) Register(ctx context.Context, inst *ServiceInstance, ttl time.Duration) error {
+	return nil
+}
+
+// Deregister : Static registry is read-only
+func (r *staticRegistry) Deregister(ctx context.Context, inst *ServiceInstance) error {
+	return nil
+}
+
+// Instances : Listed instances of service
+func (r *staticRegistry) Instances(ctx context.Context, service string) ([]*ServiceInstance, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.instances[service], nil
+}
+
+// Watch : Notified when file changed
+func (r *staticRegistry) Watch(ctx context.Context, service string, fn func()) error {
+	r.lock.Lock()
+	id := r.nextID
+	r.nextID++
+	r.watchers[id] = fn
+	r.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.lock.Lock()
+		delete(r.watchers, id)
+		r.lock.Unlock()
+	}()
+
+	return nil
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,309 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file enginetest.go
+ * @package enginetest
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+// Package enginetest : In-memory HTTP test server of engine routes, kept out of service binaries
+package enginetest
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/drnp/deuterium/engine"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+const (
+	// AppName : Application created by test server if none exists
+	AppName = "engine.test"
+)
+
+/* {{{ [Server] */
+
+// Server : In-memory HTTP server for route tests, no port bound
+type Server struct {
+	tb     testing.TB
+	server *engine.HTTPServer
+	ln     *fasthttputil.InmemoryListener
+	client *fasthttp.Client
+}
+
+// NewServer : Serve routes of given server (with all middlewares) over in-memory listener
+/* {{{ [NewServer] */
+func NewServer(tb testing.TB, srv *engine.HTTPServer) *Server {
+	tb.Helper()
+	if engine.App() == nil {
+		engine.NewApp(AppName).Silence()
+	}
+
+	if srv == nil {
+		srv = engine.NewHTTPServer("")
+	}
+
+	ln := fasthttputil.NewInmemoryListener()
+	go srv.Serve(ln)
+
+	ts := &Server{
+		tb:     tb,
+		server: srv,
+		ln:     ln,
+		client: &fasthttp.Client{
+			Dial: func(addr string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		},
+	}
+	tb.Cleanup(ts.Close)
+
+	return ts
+}
+
+/* }}} */
+
+// Close : Stop test server
+func (ts *Server) Close() {
+	ts.ln.Close()
+}
+
+// Request : Start request with given method and path
+func (ts *Server) Request(method, path string) *Request {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI("http://test" + path)
+
+	return &Request{
+		ts:  ts,
+		req: req,
+	}
+}
+
+// Get : Start GET request
+func (ts *Server) Get(path string) *Request {
+	return ts.Request(fasthttp.MethodGet, path)
+}
+
+// Post : Start POST request
+func (ts *Server) Post(path string) *Request {
+	return ts.Request(fasthttp.MethodPost, path)
+}
+
+// Put : Start PUT request
+func (ts *Server) Put(path string) *Request {
+	return ts.Request(fasthttp.MethodPut, path)
+}
+
+// Patch : Start PATCH request
+func (ts *Server) Patch(path string) *Request {
+	return ts.Request(fasthttp.MethodPatch, path)
+}
+
+// Delete : Start DELETE request
+func (ts *Server) Delete(path string) *Request {
+	return ts.Request(fasthttp.MethodDelete, path)
+}
+
+/* }}} */
+
+/* {{{ [Request] */
+
+// Request : Fluent request builder
+type Request struct {
+	ts  *Server
+	req *fasthttp.Request
+}
+
+// Header : Set request header
+func (r *Request) Header(key, value string) *Request {
+	r.req.Header.Set(key, value)
+
+	return r
+}
+
+// Query : Add query argument
+func (r *Request) Query(key, value string) *Request {
+	r.req.URI().QueryArgs().Add(key, value)
+
+	return r
+}
+
+// Body : Set raw body with content type
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.req.Header.SetContentType(contentType)
+	r.req.SetBody(body)
+
+	return r
+}
+
+// Form : Set urlencoded form body
+func (r *Request) Form(values map[string]string) *Request {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+
+	for k, v := range values {
+		args.Add(k, v)
+	}
+
+	return r.Body("application/x-www-form-urlencoded", args.QueryString())
+}
+
+// JSON : Set JSON encoded body
+func (r *Request) JSON(v interface{}) *Request {
+	r.ts.tb.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		r.ts.tb.Fatalf("Encode JSON body failed : %s", err.Error())
+	}
+
+	return r.Body("application/json", body)
+}
+
+// Do : Send request
+func (r *Request) Do() *Response {
+	r.ts.tb.Helper()
+	resp := &fasthttp.Response{}
+	if len(r.req.Header.Peek("Accept")) == 0 {
+		r.req.Header.Set("Accept", "application/json")
+	}
+
+	err := r.ts.client.Do(r.req, resp)
+	if err != nil {
+		r.ts.tb.Fatalf("HTTP test request %s %s failed : %s", r.req.Header.Method(), r.req.URI().RequestURI(), err.Error())
+	}
+
+	return &Response{
+		tb:   r.ts.tb,
+		resp: resp,
+	}
+}
+
+/* }}} */
+
+/* {{{ [Response] */
+
+// Response : Response with assertions
+type Response struct {
+	tb       testing.TB
+	resp     *fasthttp.Response
+	envelope *engine.HTTPResponseEnvelope
+	data     json.RawMessage
+}
+
+// Raw : Get original response
+func (r *Response) Raw() *fasthttp.Response {
+	return r.resp
+}
+
+// StatusCode : Get HTTP status code
+func (r *Response) StatusCode() int {
+	return r.resp.StatusCode()
+}
+
+// Header : Get response header
+func (r *Response) Header(key string) string {
+	return string(r.resp.Header.Peek(key))
+}
+
+// Envelope : Decode response body as envelope
+func (r *Response) Envelope() *engine.HTTPResponseEnvelope {
+	r.tb.Helper()
+	if r.envelope == nil {
+		var e struct {
+			engine.HTTPResponseEnvelope
+			Data json.RawMessage `json:"data"`
+		}
+
+		err := json.Unmarshal(r.resp.Body(), &e)
+		if err != nil {
+			r.tb.Fatalf("Decode envelope failed : %s, body : %s", err.Error(), r.resp.Body())
+		}
+
+		r.envelope = &e.HTTPResponseEnvelope
+		r.data = e.Data
+		json.Unmarshal(e.Data, &r.envelope.Data)
+	}
+
+	return r.envelope
+}
+
+// Decode : Decode data of envelope into v
+func (r *Response) Decode(v interface{}) *Response {
+	r.tb.Helper()
+	r.Envelope()
+	err := json.Unmarshal(r.data, v)
+	if err != nil {
+		r.tb.Fatalf("Decode envelope data failed : %s", err.Error())
+	}
+
+	return r
+}
+
+// AssertStatus : Check HTTP status code
+func (r *Response) AssertStatus(status int) *Response {
+	r.tb.Helper()
+	if r.resp.StatusCode() != status {
+		r.tb.Errorf("Expected HTTP status %d, got %d, body : %s", status, r.resp.StatusCode(), r.resp.Body())
+	}
+
+	return r
+}
+
+// AssertCode : Check business code of envelope
+func (r *Response) AssertCode(code int) *Response {
+	r.tb.Helper()
+	e := r.Envelope()
+	if e.Code != code {
+		r.tb.Errorf("Expected envelope code %d, got %d, message : %s", code, e.Code, e.Message)
+	}
+
+	return r
+}
+
+// AssertHeader : Check response header
+func (r *Response) AssertHeader(key, value string) *Response {
+	r.tb.Helper()
+	if v := r.Header(key); v != value {
+		r.tb.Errorf("Expected header <%s> to be %q, got %q", key, value, v)
+	}
+
+	return r
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,139 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file enginetest_test.go
+ * @package enginetest_test
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package enginetest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/drnp/deuterium/engine"
+	"github.com/drnp/deuterium/engine/enginetest"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestServer(t *testing.T) *enginetest.Server {
+	srv := engine.NewHTTPServer("")
+	srv.SetRoutes(&engine.HTTPRoute{
+		Name:   "Hello",
+		Method: "GET",
+		Path:   "/hello",
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			e := engine.AcquireHTTPEnvelope()
+			e.Data = map[string]interface{}{
+				"name": engine.HTTPArgString(ctx, "name"),
+			}
+			engine.HTTPEnvelope(ctx, e)
+		},
+	})
+	srv.EnableJSONRPC("")
+
+	// Application created by test server
+	ts := enginetest.NewServer(t, srv)
+	engine.RegisterHandler("test.echo", func(msg *engine.UniformMessage) (*engine.ResultMessage, error) {
+		return engine.NewResultMessage("echo", false), nil
+	})
+	engine.RegisterHandler("test.hidden", func(msg *engine.UniformMessage) (*engine.ResultMessage, error) {
+		return engine.NewResultMessage("hidden", false), nil
+	})
+	engine.RegisterHandler("test.internal", func(msg *engine.UniformMessage) (*engine.ResultMessage, error) {
+		return engine.NewResultMessage("internal", false), nil
+	})
+	engine.AllowSenders("test.internal", "main")
+	engine.ExposeJSONRPC("test.echo", "test.internal")
+
+	return ts
+}
+
+func TestRouteEnvelope(t *testing.T) {
+	ts := newTestServer(t)
+
+	var data struct {
+		Name string `json:"name"`
+	}
+	ts.Get("/hello").Query("name", "deuterium").Do().
+		AssertStatus(http.StatusOK).
+		AssertCode(0).
+		Decode(&data)
+	if data.Name != "deuterium" {
+		t.Errorf("Expected name deuterium, got %q", data.Name)
+	}
+
+	ts.Get("/missing").Do().AssertStatus(http.StatusNotFound)
+}
+
+func TestJSONRPCExposure(t *testing.T) {
+	ts := newTestServer(t)
+
+	cases := []struct {
+		method string
+		code   int
+		result string
+	}{
+		{"test.echo", 0, "echo"},
+		{"test.hidden", -32601, ""},
+		{"test.internal", engine.ErrorCodeForbidden, ""},
+	}
+	for _, c := range cases {
+		resp := ts.Post("/jsonrpc").JSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  c.method,
+			"id":      1,
+		}).Do().AssertStatus(http.StatusOK)
+
+		var r engine.JSONRPCResponse
+		if err := json.Unmarshal(resp.Raw().Body(), &r); err != nil {
+			t.Fatalf("Decode response of <%s> failed : %s", c.method, err.Error())
+		}
+
+		if c.code != 0 {
+			if r.Error == nil || r.Error.Code != c.code {
+				t.Errorf("Expected error %d of <%s>, got %+v", c.code, c.method, r.Error)
+			}
+
+			continue
+		}
+
+		if r.Error != nil || r.Result != c.result {
+			t.Errorf("Expected result %q of <%s>, got %v / %+v", c.result, c.method, r.Result, r.Error)
+		}
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,198 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file error.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Error codes, business codes of applications should be greater than ErrorCodeReserved
+const (
+	ErrorCodeOK          = 0
+	ErrorCodeInternal    = 1
+	ErrorCodePanic       = 2
+	ErrorCodeBadRequest  = 3
+	ErrorCodeNotFound    = 4
+	ErrorCodeForbidden   = 5
+	ErrorCodeTimeout     = 6
+	ErrorCodeUnavailable = 7
+	ErrorCodeConflict    = 8
+	ErrorCodeReserved    = 1000
+)
+
+// Error : Typed error carrying business code and HTTP status
+type Error struct {
+	Code        int
+	HTTPStatus  int
+	Message     string
+	ErrorPrompt string
+	Details     map[string]interface{}
+	cause       error
+}
+
+// NewError : Create error with code, HTTP status and message
+/* {{{ [NewError] */
+func NewError(code, httpStatus int, message string) *Error {
+	if httpStatus == 0 {
+		httpStatus = http.StatusInternalServerError
+	}
+
+	return &Error{
+		Code:       code,
+		HTTPStatus: httpStatus,
+		Message:    message,
+	}
+}
+
+/* }}} */
+
+// Errorf : Create error with formatted message
+/* {{{ [Errorf] */
+func Errorf(code, httpStatus int, format string, args ...interface{}) *Error {
+	return NewError(code, httpStatus, fmt.Sprintf(format, args...))
+}
+
+/* }}} */
+
+// AsError : Convert any error into *Error, untyped errors are treated as internal
+/* {{{ [AsError] */
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return NewError(ErrorCodeInternal, http.StatusInternalServerError, err.Error()).Wrap(err)
+}
+
+/* }}} */
+
+/* {{{ [Error::Methods] */
+
+// Error : Implement error interface
+func (e *Error) Error() string {
+	if e.cause != nil && e.cause.Error() != e.Message {
+		return fmt.Sprintf("%s : %s", e.Message, e.cause.Error())
+	}
+
+	return e.Message
+}
+
+// Unwrap : Get original error
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Wrap : Set original error
+func (e *Error) Wrap(err error) *Error {
+	e.cause = err
+
+	return e
+}
+
+// WithPrompt : Set error prompt shown to users
+func (e *Error) WithPrompt(prompt string) *Error {
+	e.ErrorPrompt = prompt
+
+	return e
+}
+
+// WithDetail : Add detail value
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+
+	e.Details[key] = value
+
+	return e
+}
+
+// Is : Errors with the same code are regarded as equal
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return t.Code == e.Code
+}
+
+/* }}} */
+
+/* {{{ [Predefined errors] */
+
+// Predefined errors, use errors.Is to compare
+var (
+	ErrInternal    = NewError(ErrorCodeInternal, http.StatusInternalServerError, "Internal error")
+	ErrPanic       = NewError(ErrorCodePanic, http.StatusInternalServerError, "Internal error (panic)")
+	ErrBadRequest  = NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Bad request")
+	ErrNotFound    = NewError(ErrorCodeNotFound, http.StatusNotFound, "Not found")
+	ErrForbidden   = NewError(ErrorCodeForbidden, http.StatusForbidden, "Forbidden")
+	ErrTimeout     = NewError(ErrorCodeTimeout, http.StatusGatewayTimeout, "Timeout")
+	ErrUnavailable = NewError(ErrorCodeUnavailable, http.StatusServiceUnavailable, "Service unavailable")
+	ErrConflict    = NewError(ErrorCodeConflict, http.StatusConflict, "Conflict")
+)
+
+/* }}} */
+
+// recoverError : Convert recovered value into error and log stack trace
+/* {{{ [recoverError] */
+func recoverError(r interface{}, where string) *Error {
+	Logger().WithField("stack", string(debug.Stack())).Errorf("%s panic recovered : %v", where, r)
+	e := NewError(ErrPanic.Code, ErrPanic.HTTPStatus, ErrPanic.Message)
+	if err, ok := r.(error); ok {
+		e.Wrap(err)
+	} else {
+		e.Wrap(fmt.Errorf("%v", r))
+	}
+
+	return e
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,454 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file fieldset.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Sparse fieldset query arguments
+const (
+	HTTPFieldsParam = "fields"
+	HTTPExpandParam = "expand"
+)
+
+// HTTPFieldset : Selected fields of data, keyed by JSON names. Marshals into XML elements as well
+type HTTPFieldset map[string]interface{}
+
+// fieldTree : Requested (nested) fields, empty node means the whole value
+type fieldTree map[string]fieldTree
+
+// fieldsetField : Cached exported field of struct
+type fieldsetField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var (
+	fieldsetTypes sync.Map
+)
+
+/* {{{ [Expand] */
+
+// HTTPExpands : Related resources requested by expand= argument
+func HTTPExpands(ctx *fasthttp.RequestCtx) []string {
+	return splitFieldList(string(ctx.QueryArgs().Peek(HTTPExpandParam)))
+}
+
+// HTTPExpand : Whether related resource requested by expand= argument
+func HTTPExpand(ctx *fasthttp.RequestCtx, name string) bool {
+	for _, n := range HTTPExpands(ctx) {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Expand : Load related resource into data under given name if requested by expand= argument
+/* {{{ [HTTPResponseEnvelope::Expand] */
+func (e *HTTPResponseEnvelope) Expand(ctx *fasthttp.RequestCtx, name string, loader func() (interface{}, error)) error {
+	if !HTTPExpand(ctx, name) {
+		return nil
+	}
+
+	v, err := loader()
+	if err != nil {
+		return err
+	}
+
+	if e.expanded == nil {
+		e.expanded = make(map[string]interface{})
+	}
+
+	e.expanded[name] = v
+
+	return nil
+}
+
+/* }}} */
+
+/* }}} */
+
+// applyFieldset : Merge expanded resources and keep fields requested by fields= argument
+/* {{{ [HTTPResponseEnvelope::applyFieldset] */
+func (e *HTTPResponseEnvelope) applyFieldset(ctx *fasthttp.RequestCtx) {
+	fields := splitFieldList(string(ctx.QueryArgs().Peek(HTTPFieldsParam)))
+	if len(fields) == 0 && len(e.expanded) == 0 {
+		return
+	}
+
+	if e.Data == nil {
+		return
+	}
+
+	if len(e.expanded) > 0 {
+		m, ok := selectFields(reflect.ValueOf(e.Data), nil).(HTTPFieldset)
+		if !ok {
+			Logger().Debugf("HTTP fieldset : Cannot expand into data of type %T", e.Data)
+		} else {
+			for k, v := range e.expanded {
+				m[k] = v
+			}
+
+			e.Data = m
+		}
+	}
+
+	if len(fields) == 0 {
+		return
+	}
+
+	tree := make(fieldTree)
+	for _, f := range fields {
+		node := tree
+		for _, part := range strings.Split(f, ".") {
+			if part == "" {
+				continue
+			}
+
+			sub, ok := node[part]
+			if !ok {
+				sub = make(fieldTree)
+				node[part] = sub
+			}
+
+			node = sub
+		}
+	}
+
+	for k := range e.expanded {
+		if _, ok := tree[k]; !ok {
+			tree[k] = make(fieldTree)
+		}
+	}
+
+	e.Data = selectFields(reflect.ValueOf(e.Data), tree)
+
+	return
+}
+
+/* }}} */
+
+// selectFields : Copy requested fields of value, nil tree converts structs and maps into fieldset with all fields
+/* {{{ [selectFields] */
+func selectFields(v reflect.Value, tree fieldTree) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(HTTPFieldset)
+		for _, f := range fieldsetFields(v.Type()) {
+			sub, ok := tree[f.name]
+			if tree != nil && !ok {
+				continue
+			}
+
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok {
+				continue
+			}
+
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+
+			out[f.name] = fieldValue(fv, sub)
+		}
+
+		return out
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		out := make(HTTPFieldset)
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			sub, ok := tree[key]
+			if tree != nil && !ok {
+				continue
+			}
+
+			out[key] = fieldValue(iter.Value(), sub)
+		}
+
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 || tree == nil {
+			break
+		}
+
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+
+		out := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out[i] = selectFields(v.Index(i), tree)
+		}
+
+		return out
+	}
+
+	return v.Interface()
+}
+
+/* }}} */
+
+// fieldValue : Whole value if no sub fields requested
+func fieldValue(v reflect.Value, sub fieldTree) interface{} {
+	if len(sub) == 0 {
+		if !v.IsValid() || !v.CanInterface() {
+			return nil
+		}
+
+		return v.Interface()
+	}
+
+	return selectFields(v, sub)
+}
+
+// fieldByIndex : Field of (embedded) struct, false if embedded pointer is nil
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+
+				v = v.Elem()
+			}
+		}
+
+		v = v.Field(idx)
+	}
+
+	return v, true
+}
+
+// fieldsetFields : Exported fields of struct type by JSON names, cached per type
+/* {{{ [fieldsetFields] */
+func fieldsetFields(t reflect.Type) []fieldsetField {
+	if cached, ok := fieldsetTypes.Load(t); ok {
+		return cached.([]fieldsetField)
+	}
+
+	var fields []fieldsetField
+	seen := make(map[string]bool)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			parts := strings.Split(tag, ",")
+			name := parts[0]
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			idx := append(append([]int(nil), index...), i)
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				// Promoted fields of embedded struct
+				walk(ft, idx)
+
+				continue
+			}
+
+			if sf.PkgPath != "" {
+				// Unexported
+				continue
+			}
+
+			if name == "" {
+				name = sf.Name
+			}
+
+			if seen[name] {
+				// Outer field wins
+				continue
+			}
+
+			seen[name] = true
+			f := fieldsetField{
+				name:  name,
+				index: idx,
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					f.omitEmpty = true
+				}
+			}
+
+			fields = append(fields, f)
+		}
+	}
+
+	walk(t, nil)
+	fieldsetTypes.Store(t, fields)
+
+	return fields
+}
+
+/* }}} */
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+func splitFieldList(s string) []string {
+	var list []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			list = append(list, f)
+		}
+	}
+
+	return list
+}
+
+// MarshalXML : Keys as child elements in order, lists as repeated elements
+/* {{{ [HTTPFieldset::MarshalXML] */
+func (fs HTTPFieldset) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(fs))
+	for k := range fs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		err = encodeFieldsetXML(enc, k, fs[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+/* }}} */
+
+func encodeFieldsetXML(enc *xml.Encoder, name string, v interface{}) error {
+	el := xml.StartElement{Name: xml.Name{Local: name}}
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return enc.EncodeElement(HTTPFieldset(t), el)
+	case []interface{}:
+		for _, item := range t {
+			if err := encodeFieldsetXML(enc, name, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			if err := encodeFieldsetXML(enc, name, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if rv.Kind() == reflect.Map {
+		// Maps of other value types
+		if m, ok := selectFields(rv, nil).(HTTPFieldset); ok {
+			return enc.EncodeElement(m, el)
+		}
+
+		return fmt.Errorf("Unsupported map type %T in XML", v)
+	}
+
+	return enc.EncodeElement(v, el)
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
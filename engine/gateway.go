@@ -0,0 +1,199 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file gateway.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Gateway types of route
+const (
+	GatewayCall      = "call"
+	GatewayTask      = "task"
+	GatewayNotify    = "notify"
+	GatewayOperation = "operation"
+)
+
+// HTTPPermissionChecker : Check whether request has all required permission bits
+type HTTPPermissionChecker func(ctx *fasthttp.RequestCtx, required uint64) bool
+
+var (
+	httpPermissionChecker HTTPPermissionChecker
+)
+
+// SetHTTPPermissionChecker : Set checker of route permissions
+func SetHTTPPermissionChecker(checker HTTPPermissionChecker) {
+	httpPermissionChecker = checker
+}
+
+// mwPermission : Reject request without required permissions of route, checked only if checker set unless strict
+/* {{{ [mwPermission] */
+func mwPermission(h fasthttp.RequestHandler, required uint64, strict bool) fasthttp.RequestHandler {
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		if httpPermissionChecker == nil {
+			if !strict {
+				h(ctx)
+
+				return
+			}
+
+			Logger().Warnf("Route <%s> requires permissions %d, but no permission checker set", ctx.Path(), required)
+			HTTPEnvelopeError(ctx, ErrForbidden)
+
+			return
+		}
+
+		if !httpPermissionChecker(ctx, required) {
+			HTTPEnvelopeError(ctx, ErrForbidden)
+
+			return
+		}
+
+		h(ctx)
+
+		return
+	})
+}
+
+/* }}} */
+
+// parseGateway : Split "receiver:method" of gateway
+func parseGateway(gateway string) (string, string, error) {
+	idx := strings.LastIndex(gateway, ":")
+	if idx <= 0 || idx == len(gateway)-1 {
+		return "", "", fmt.Errorf("Invalid gateway <%s>, \"receiver:method\" required", gateway)
+	}
+
+	return gateway[:idx], gateway[idx+1:], nil
+}
+
+// gatewayHandler : Handler forwarding request arguments to RPC / task / notify target
+/* {{{ [gatewayHandler] */
+func gatewayHandler(route *HTTPRoute) (fasthttp.RequestHandler, error) {
+	receiver, method, err := parseGateway(route.Gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	gatewayType := strings.ToLower(route.GatewayType)
+	switch gatewayType {
+	case "":
+		gatewayType = GatewayCall
+	case GatewayCall, GatewayTask, GatewayNotify, GatewayOperation:
+	default:
+		return nil, fmt.Errorf("Invalid gateway type <%s>", route.GatewayType)
+	}
+
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		args := HTTPArgsOf(ctx)
+		if err := args.Err(); err != nil {
+			HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Error when parsing request").Wrap(err))
+
+			return
+		}
+
+		e := AcquireHTTPEnvelope()
+		msg := NewUniformMessage(args.Map(), false).WithContext(HTTPContext(ctx))
+		switch gatewayType {
+		case GatewayOperation:
+			op, err := msg.operate(receiver, method, operationOwner(ctx))
+			if err != nil {
+				e.SetError(ErrUnavailable)
+			} else {
+				operationAccepted(ctx, e, op)
+			}
+		case GatewayTask, GatewayNotify:
+			var err error
+			if gatewayType == GatewayTask {
+				err = msg.Task(receiver, method)
+			} else {
+				err = msg.Notify(receiver, method)
+			}
+
+			if err != nil {
+				e.SetError(ErrUnavailable)
+			} else {
+				e.HTTPStatus = http.StatusAccepted
+				e.Message = "Accepted"
+				e.Data = map[string]string{"id": msg.ID}
+			}
+		default:
+			r, err := msg.Call(receiver, method)
+			if r == nil {
+				// Transport failed
+				var ee *Error
+				if errors.As(err, &ee) {
+					e.SetError(ee)
+				} else {
+					e.SetError(NewError(ErrorCodeUnavailable, http.StatusBadGateway, "Gateway call failed").Wrap(err))
+				}
+			} else {
+				e.Code = r.Code
+				e.HTTPStatus = r.HTTPStatus
+				if r.Message != "" {
+					e.Message = r.Message
+				}
+
+				e.ErrorPrompt = r.ErrorPrompt
+				e.Details = r.Details
+				if r.Length() > 0 {
+					var data interface{}
+					if err := r.Unmarshal(&data); err != nil {
+						e.SetError(NewError(ErrorCodeInternal, http.StatusBadGateway, "Invalid gateway response").Wrap(err))
+					} else {
+						e.Data = data
+					}
+				}
+			}
+		}
+
+		HTTPEnvelope(ctx, e)
+
+		return
+	}), nil
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
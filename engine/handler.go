@@ -31,7 +31,10 @@
 
 package engine
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // UniformHandlerFunc : RPC / Task / Notify handler
 type UniformHandlerFunc func(*UniformMessage) (*ResultMessage, error)
@@ -41,6 +44,10 @@ type UniformMsgHandler struct {
 	method      string
 	hdr         UniformHandlerFunc
 	concurrency int64
+	// Allowed senders, nil for any
+	senders map[string]bool
+	// Registered by RegisterStreamHandler
+	streaming bool
 }
 
 var handlers = make(map[string]*UniformMsgHandler)
@@ -81,6 +88,32 @@ func GetHandler(method string) *UniformMsgHandler {
 	return h
 }
 
+// call : Run handler function wrapped by interceptors, panics are recovered as ErrPanic
+func (h *UniformMsgHandler) call(msg *UniformMessage) (ret *ResultMessage, err error) {
+	if msg.Operation != "" {
+		// Runs after panic recovered
+		defer func() {
+			msg.operationFinish(ret, err)
+		}()
+
+		e := updateOperation(msg.Context(), msg.Operation, func(op *Operation) {
+			op.Status = OperationStatusRunning
+		})
+		if e != nil {
+			Logger().Errorf("Operation <%s> : Update status failed : %s", msg.Operation, e.Error())
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ret = nil
+			err = recoverError(r, fmt.Sprintf("Handler <%s>", h.method))
+		}
+	}()
+
+	return intercept(h.method, msg.transport, h.hdr)(msg)
+}
+
 // SetConcurrency : Set concurrency of message handler
 func SetConcurrency(method string, concurrency int64) {
 	if method == "" {
@@ -32,16 +32,19 @@
 package engine
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"net"
 	"reflect"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fasthttp/router"
 	"github.com/valyala/fasthttp"
+	"github.com/vmihailenco/msgpack"
 )
 
 const (
@@ -55,13 +58,15 @@ const (
 
 // HTTPServer : Fasthttp server
 type HTTPServer struct {
-	addr        string
-	tls         bool
-	sslCertFile string
-	sslKeyFile  string
-	server      *fasthttp.Server
-	router      *router.Router
-	routes      []*HTTPRoute
+	addr      string
+	tlsConf   *TLSConfig
+	server    *fasthttp.Server
+	router    *router.Router
+	index     *router.Router
+	routes    []*HTTPRoute
+	loaded    bool
+	listeners []*HTTPListener
+	opened    []net.Listener
 }
 
 // HTTPRoute : Route for fasthttprouter
@@ -74,14 +79,37 @@ type HTTPRoute struct {
 	Handler     fasthttp.RequestHandler
 	Permissions uint64
 	Middlewares []string
+	MaxBodySize int
+	Upload      *HTTPUploadConfig
+	Cache       *HTTPCacheConfig
+	Idempotency *HTTPIdempotencyConfig
+	Gateway     string
+	GatewayType string
+	Internal    bool
+	Timeout     time.Duration
 }
 
+const (
+	httpRouteKey = "_engine.http.route"
+)
+
+var (
+	httpLookupCtxPool = sync.Pool{
+		New: func() interface{} {
+			return new(fasthttp.RequestCtx)
+		},
+	}
+)
+
 // NewHTTPServer : Create fasthttp server by given parameters
 /* {{{ [NewHTTPServer] */
 func NewHTTPServer(addr string) *HTTPServer {
 	f := &fasthttp.Server{
 		MaxRequestBodySize: HTTPServerMaxRequestBodySize,
 		ReadTimeout:        HTTPServerReadTimeout,
+		// Bodies over limit of route handed over as stream, bounded by mwRequestBody
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	}
 	r := router.New()
 	r.OPTIONS("/*_all", mwCors)
@@ -89,7 +117,10 @@ func NewHTTPServer(addr string) *HTTPServer {
 		addr:   addr,
 		server: f,
 		router: r,
+		index:  router.New(),
 	}
+	f.HeaderReceived = server.headerReceived
+	f.ErrorHandler = httpErrorHandler
 
 	return server
 }
@@ -100,14 +131,23 @@ func NewHTTPServer(addr string) *HTTPServer {
 /* {{{ [HTTPServer::SetSSL] */
 func (s *HTTPServer) SetSSL(sslCertFile, sslKeyFile string) {
 	if sslCertFile != "" && sslKeyFile != "" {
-		s.tls = true
-		s.sslCertFile = sslCertFile
-		s.sslKeyFile = sslKeyFile
+		s.SetTLS(&TLSConfig{
+			CertFile: sslCertFile,
+			KeyFile:  sslKeyFile,
+		})
 	}
 }
 
 /* }}} */
 
+// SetTLS : Set TLS (mutual TLS if CA bundle given) configuration for HTTP server
+/* {{{ [HTTPServer::SetTLS] */
+func (s *HTTPServer) SetTLS(conf *TLSConfig) {
+	s.tlsConf = conf
+}
+
+/* }}} */
+
 // Startup : Start and serve
 /* {{{ [HTTPServer::Startup] */
 func (s *HTTPServer) Startup(logger fasthttp.Logger) {
@@ -116,32 +156,34 @@ func (s *HTTPServer) Startup(logger fasthttp.Logger) {
 	}
 
 	s.server.Handler = s.router.Handler
-	go func() {
-		var failed error
-		if s.tls == true {
-			// HTTPS
-			if s.server.Logger != nil {
-				s.server.Logger.Printf("HTTP server initialized at [%s] with SSL", s.addr)
-			}
-
-			failed = s.server.ListenAndServeTLS(s.addr, s.sslCertFile, s.sslKeyFile)
-		} else {
-			// Normal HTTP
-			if s.server.Logger != nil {
-				s.server.Logger.Printf("HTTP server initialized at [%s]", s.addr)
-			}
+	if s.tlsConf == nil {
+		s.tlsConf = TLSConfigFromConfig("http.server.tls")
+	}
 
-			failed = s.server.ListenAndServe(s.addr)
+	opened, err := s.openListeners()
+	if err != nil {
+		if s.server.Logger != nil {
+			s.server.Logger.Printf("HTTP server listen failed : %s", err.Error())
 		}
 
+		return
+	}
+
+	s.opened = opened
+	for _, ln := range opened {
 		if s.server.Logger != nil {
-			if failed != nil {
-				s.server.Logger.Printf("HTTP server listen and serve failed : %s", failed.Error())
-			}
+			s.server.Logger.Printf("HTTP server initialized at [%s]", ln.Addr())
 		}
 
-		//waiter.Done()
-	}()
+		go func(ln net.Listener) {
+			failed := s.server.Serve(ln)
+			if s.server.Logger != nil {
+				if failed != nil {
+					s.server.Logger.Printf("HTTP server serve [%s] failed : %s", ln.Addr(), failed.Error())
+				}
+			}
+		}(ln)
+	}
 
 	// Do not fly
 	time.Sleep(100 * time.Microsecond)
@@ -151,6 +193,17 @@ func (s *HTTPServer) Startup(logger fasthttp.Logger) {
 
 /* }}} */
 
+// Serve : Serve routes on given listener (in-memory listener of tests) until it closed
+/* {{{ [HTTPServer::Serve] */
+func (s *HTTPServer) Serve(ln net.Listener) error {
+	s.loadRoutes()
+	s.server.Handler = s.router.Handler
+
+	return s.server.Serve(ln)
+}
+
+/* }}} */
+
 // Shutdown : Graceful stop HTTP server
 /* {{{ [HTTPServer::Shutdown] */
 func (s *HTTPServer) Shutdown() {
@@ -179,23 +232,138 @@ func (s *HTTPServer) SetRoutes(routes ...*HTTPRoute) error {
 
 /* }}} */
 
+// lookupRoute : Find route by method and path before request body read
+/* {{{ [HTTPServer::lookupRoute] */
+func (s *HTTPServer) lookupRoute(method, path string) *HTTPRoute {
+	ctx := httpLookupCtxPool.Get().(*fasthttp.RequestCtx)
+	defer func() {
+		// Route and path parameters never seen by next lookup
+		var keys [][]byte
+		ctx.VisitUserValues(func(k []byte, v interface{}) {
+			keys = append(keys, append([]byte(nil), k...))
+		})
+
+		for _, k := range keys {
+			ctx.SetUserValueBytes(k, nil)
+		}
+
+		httpLookupCtxPool.Put(ctx)
+	}()
+
+	h, _ := s.index.Lookup(method, path, ctx)
+	if h == nil {
+		return nil
+	}
+
+	h(ctx)
+	route, _ := ctx.UserValue(httpRouteKey).(*HTTPRoute)
+
+	return route
+}
+
+/* }}} */
+
+// headerReceived : Buffer request body up to limit of route, larger (or chunked) body streamed and bounded by route.
+// Upload routes buffer little, files streamed into temporary files
+/* {{{ [HTTPServer::headerReceived] */
+func (s *HTTPServer) headerReceived(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	var (
+		conf fasthttp.RequestConfig
+		uri  fasthttp.URI
+	)
+
+	uri.Parse(nil, header.RequestURI())
+	route := s.lookupRoute(string(header.Method()), string(uri.Path()))
+	if route != nil && route.Upload != nil {
+		conf.MaxRequestBodySize = HTTPUploadBufferSize
+	} else if route != nil {
+		conf.MaxRequestBodySize = s.bodyLimit(route)
+	}
+
+	return conf
+}
+
+/* }}} */
+
+// bodyLimit : Request body limit of route, derived from upload limits if not set
+func (s *HTTPServer) bodyLimit(route *HTTPRoute) int {
+	if route.MaxBodySize > 0 {
+		return route.MaxBodySize
+	}
+
+	if route.Upload != nil {
+		if limit := route.Upload.bodyLimit(); limit > 0 {
+			return limit
+		}
+	}
+
+	return s.server.MaxRequestBodySize
+}
+
+/* }}} */
+
 // loadRoutes : Load routes into router
 /* {{{ [HTTPServer::loadRoutes] */
 func (s *HTTPServer) loadRoutes() {
+	if s.loaded {
+		return
+	}
+
+	s.loaded = true
 	for _, route := range s.routes {
-		if route.Path == "" || route.Handler == nil {
+		if route.Path == "" {
+			continue
+		}
+
+		if route.Handler == nil && route.Gateway != "" {
+			gh, err := gatewayHandler(route)
+			if err != nil {
+				App().Logger().Errorf("Load route <%s> failed : %s", route.Name, err.Error())
+
+				continue
+			}
+
+			route.Handler = gh
+		}
+
+		if route.Handler == nil {
 			continue
 		}
 
 		h := route.Handler
+		if route.Cache != nil {
+			h = mwCache(h, route.Cache)
+		}
+
+		if route.Idempotency != nil {
+			h = mwIdempotency(h, route.Idempotency)
+		}
+
+		if route.Permissions != 0 {
+			// Gateway routes expose internal handlers, denied without checker
+			h = mwPermission(h, route.Permissions, route.Gateway != "")
+		}
+
+		if route.Internal {
+			h = mwInternal(h)
+		}
+
+		h = mwRecover(h)
+		if route.Timeout > 0 {
+			h = mwTimeout(h, route.Timeout)
+		}
+
 		// AccessLog
 		if App().Config().GetBool("http.server.access_log") {
 			h = mwAccessLog(h)
 		}
 
+		h = mwRequestBody(h, route.Upload, s.bodyLimit(route))
+
 		uris := []string{route.Path}
 		uris = append(uris, route.Aliases...)
 		for _, uri := range uris {
+			s.indexRoute(route, uri)
 			switch strings.ToLower(route.Method) {
 			case "post":
 				s.router.POST(uri, h)
@@ -224,6 +392,38 @@ func (s *HTTPServer) loadRoutes() {
 
 /* }}} */
 
+// httpErrorHandler : Respond envelope when request cannot be read
+func httpErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	if err == fasthttp.ErrBodyTooLarge {
+		HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, fasthttp.StatusRequestEntityTooLarge, "Request body too large").Wrap(err))
+
+		return
+	}
+
+	HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, fasthttp.StatusBadRequest, "Error when parsing request").Wrap(err))
+
+	return
+}
+
+// indexRoute : Put route into lookup index
+/* {{{ [HTTPServer::indexRoute] */
+func (s *HTTPServer) indexRoute(route *HTTPRoute, uri string) {
+	method := strings.ToUpper(route.Method)
+	switch method {
+	case "POST", "DELETE", "PUT", "OPTIONS", "PATCH", "HEAD":
+	default:
+		method = "GET"
+	}
+
+	s.index.Handle(method, uri, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue(httpRouteKey, route)
+	})
+
+	return
+}
+
+/* }}} */
+
 /* {{{ [HTTPMiddlewares] */
 
 // mwCors : CORS
@@ -236,6 +436,23 @@ func mwCors(ctx *fasthttp.RequestCtx) {
 	return
 }
 
+// mwRecover : Recover panic in handler, respond error envelope instead of half-written body
+func mwRecover(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			if r := recover(); r != nil {
+				err := recoverError(r, fmt.Sprintf("HTTP <%s %s>", ctx.Method(), ctx.Path()))
+				ctx.Response.Reset()
+				HTTPEnvelopeError(ctx, err)
+			}
+		}()
+
+		h(ctx)
+
+		return
+	})
+}
+
 // mwAccessLog : Access log
 func mwAccessLog(h fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
@@ -264,18 +481,37 @@ func mwAccessLog(h fasthttp.RequestHandler) fasthttp.RequestHandler {
 
 // HTTPResponseEnvelope : Response body envelope
 type HTTPResponseEnvelope struct {
-	Code           int             `json:"code" xml:"code"`
-	HTTPStatus     int             `json:"http_status" xml:"http_status"`
-	StartTimestamp int64           `json:"start_timestamp" xml:"start_timestamp"`
-	EndTimestamp   int64           `json:"end_timestamp" xml:"end_timestamp"`
-	ElapsedTime    int64           `json:"elapsed_time" xml:"elapsed_time"`
-	Message        string          `json:"message,omitempty" xml:"message"`
-	ErrorPrompt    string          `json:"error_prompt,omitempty" xml:"error_prompt,omitempty"`
-	Links          []string        `json:"linkes,omitempty" xml:"links"`
-	Pagination     *HTTPPagination `json:"pagination,omitempty" xml:"pagination,omitempty"`
-	Data           interface{}     `json:"data" xml:"data"`
+	Code           int                    `json:"code" xml:"code"`
+	HTTPStatus     int                    `json:"http_status" xml:"http_status"`
+	StartTimestamp int64                  `json:"start_timestamp" xml:"start_timestamp"`
+	EndTimestamp   int64                  `json:"end_timestamp" xml:"end_timestamp"`
+	ElapsedTime    int64                  `json:"elapsed_time" xml:"elapsed_time"`
+	Message        string                 `json:"message,omitempty" xml:"message"`
+	ErrorPrompt    string                 `json:"error_prompt,omitempty" xml:"error_prompt,omitempty"`
+	Links          []*HTTPLink            `json:"links,omitempty" xml:"links,omitempty"`
+	Pagination     *HTTPPagination        `json:"pagination,omitempty" xml:"pagination,omitempty"`
+	Details        map[string]interface{} `json:"details,omitempty" xml:"-"`
+	Data           interface{}            `json:"data" xml:"data"`
+	expanded       map[string]interface{}
 }
 
+// SetError : Fill code, status and messages from error
+/* {{{ [HTTPResponseEnvelope::SetError] */
+func (e *HTTPResponseEnvelope) SetError(err error) {
+	ee := AsError(err)
+	if ee == nil {
+		return
+	}
+
+	e.Code = ee.Code
+	e.HTTPStatus = ee.HTTPStatus
+	e.Message = ee.Message
+	e.ErrorPrompt = ee.ErrorPrompt
+	e.Details = ee.Details
+}
+
+/* }}} */
+
 // HTTPPagination : Pagination variables
 type HTTPPagination struct {
 	TotalEntries   int64      `json:"total_entries" xml:"total_entries"`
@@ -298,6 +534,11 @@ const (
 	defaultPerPage    = 20
 )
 
+var (
+	httpUploadFileType  = reflect.TypeOf(&HTTPUploadFile{})
+	httpUploadFilesType = reflect.TypeOf([]*HTTPUploadFile{})
+)
+
 func parseRequestBodyField(midField map[string]interface{}, field []string) []string {
 	for k := range midField {
 		field = append(field, k)
@@ -330,6 +571,26 @@ func HTTPParseRequestBody(ctx *fasthttp.RequestCtx, obj interface{}) ([]string,
 	} else {
 		// Form data
 		args := ctx.Request.PostArgs()
+		var files []*HTTPUploadFile
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			values, err := httpMultipartValues(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			args = &fasthttp.Args{}
+			for k, vs := range values {
+				for _, v := range vs {
+					args.Add(k, v)
+				}
+			}
+
+			files, err = HTTPUploadFiles(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		t := reflect.TypeOf(obj)
 		if t.Kind() == reflect.Ptr {
 			t = t.Elem()
@@ -346,7 +607,22 @@ func HTTPParseRequestBody(ctx *fasthttp.RequestCtx, obj interface{}) ([]string,
 						pname = tt.Name
 					}
 
-					if args.Has(pname) {
+					if f.Type() == httpUploadFileType || f.Type() == httpUploadFilesType {
+						for _, file := range files {
+							if file.Field != pname {
+								continue
+							}
+
+							field = append(field, pname)
+							if f.Kind() == reflect.Slice {
+								f.Set(reflect.Append(f, reflect.ValueOf(file)))
+							} else {
+								f.Set(reflect.ValueOf(file))
+
+								break
+							}
+						}
+					} else if args.Has(pname) {
 						field = append(field, pname)
 						switch f.Kind() {
 						case reflect.Bool:
@@ -405,6 +681,8 @@ func HTTPEnvelope(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope) error {
 	*/
 
 	e.ElapsedTime = e.EndTimestamp - e.StartTimestamp
+	e.paginationLinks(ctx)
+	e.applyFieldset(ctx)
 
 	ctx.SetStatusCode(e.HTTPStatus)
 	ctx.ResetBody()
@@ -428,7 +706,15 @@ func HTTPEnvelope(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope) error {
 	// BUG : Hack here ~ zhanghao05 12/03/2019
 	//delete(accepts, "application/xml")
 	accepts["application/json"] = true
-	if accepts["application/xml"] == true {
+	if accepts["application/msgpack"] == true || accepts["application/x-msgpack"] == true {
+		// Keys named as JSON
+		ctx.Response.Header.Set("Content-Type", "application/msgpack")
+		var buf bytes.Buffer
+		err = msgpack.NewEncoder(&buf).UseJSONTag(true).Encode(e)
+		if err == nil {
+			ctx.Write(buf.Bytes())
+		}
+	} else if accepts["application/xml"] == true {
 		ctx.Response.Header.Set("Content-Type", "application/xml")
 		body, err = xml.Marshal(e)
 		if err == nil {
@@ -451,6 +737,17 @@ func HTTPEnvelope(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope) error {
 
 /* }}} */
 
+// HTTPEnvelopeError : Build error envelope to response
+/* {{{ [HTTPEnvelopeError] */
+func HTTPEnvelopeError(ctx *fasthttp.RequestCtx, err error) error {
+	e := AcquireHTTPEnvelope()
+	e.SetError(err)
+
+	return HTTPEnvelope(ctx, e)
+}
+
+/* }}} */
+
 // HTTPUnEnvelope : Unmarshal envelope from context
 /* {{{ [HTTPUnEnvelope] */
 func HTTPUnEnvelope(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope) error {
@@ -467,6 +764,8 @@ func HTTPUnEnvelope(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope) error {
 		err = xml.Unmarshal(ctx.Response.Body(), e)
 	case "application/json":
 		err = json.Unmarshal(ctx.Response.Body(), e)
+	case "application/msgpack":
+		err = msgpack.NewDecoder(bytes.NewReader(ctx.Response.Body())).UseJSONTag(true).Decode(e)
 	default:
 	}
 
@@ -479,151 +778,17 @@ func HTTPUnEnvelope(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope) error {
 
 // HTTPArgString : Get string value from arguments by given key
 func HTTPArgString(ctx *fasthttp.RequestCtx, key string) string {
-	// UserValue
-	uv := ctx.UserValue(key)
-	uvStr, ok := uv.(string)
-	if ok {
-		return uvStr
-	}
-
-	// QueryString
-	args := ctx.QueryArgs()
-	qb := args.Peek(key)
-	if qb != nil {
-		return string(qb)
-	}
-
-	// Query body
-	var (
-		body = map[string]interface{}{
-			key: nil,
-		}
-		bv interface{}
-	)
-
-	switch strings.ToLower(string(ctx.Request.Header.ContentType())) {
-	case "application/json":
-		json.Unmarshal(ctx.Request.Body(), &body)
-		bv = body[key]
-	case "application/xml":
-		xml.Unmarshal(ctx.Request.Body(), &body)
-		bv = body[key]
-	default:
-		args = ctx.PostArgs()
-		bvb := args.Peek(key)
-		if bvb != nil {
-			return string(bvb)
-		}
-	}
-
-	bvStr, ok := bv.(string)
-	if ok {
-		return bvStr
-	}
-
-	return ""
+	return HTTPArgsOf(ctx).String(key)
 }
 
 // HTTPArgInt : Get int value from arguments by given key
 func HTTPArgInt(ctx *fasthttp.RequestCtx, key string) int64 {
-	// UserValue
-	uv := ctx.UserValue(key)
-	uvStr, ok := uv.(string)
-	if ok {
-		uvInt, _ := strconv.ParseInt(uvStr, 10, 0)
-		return uvInt
-	}
-
-	// QueryString
-	args := ctx.QueryArgs()
-	qb := args.Peek(key)
-	if qb != nil {
-		uvInt, _ := strconv.ParseInt(string(qb), 10, 0)
-		return uvInt
-	}
-
-	// Query body
-	var (
-		body = map[string]interface{}{
-			key: nil,
-		}
-		bv interface{}
-	)
-
-	switch strings.ToLower(string(ctx.Request.Header.ContentType())) {
-	case "application/json":
-		json.Unmarshal(ctx.Request.Body(), &body)
-		bv = body[key]
-	case "application/xml":
-		xml.Unmarshal(ctx.Request.Body(), &body)
-		bv = body[key]
-	default:
-		args = ctx.PostArgs()
-		bvb := args.Peek(key)
-		if bvb != nil {
-			bvInt, _ := strconv.ParseInt(string(bvb), 10, 0)
-			return bvInt
-		}
-	}
-
-	switch bv.(type) {
-	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		return bv.(int64)
-	case float32, float64:
-		return int64(bv.(float64))
-	}
-
-	return 0
+	return HTTPArgsOf(ctx).Int(key)
 }
 
 // HTTPArgBool : Get bool value from arguments by given key
 func HTTPArgBool(ctx *fasthttp.RequestCtx, key string) bool {
-	// UserValue
-	uv := ctx.UserValue(key)
-	uvStr, ok := uv.(string)
-	if ok {
-		uvBool, _ := strconv.ParseBool(uvStr)
-		return uvBool
-	}
-
-	// QueryString
-	args := ctx.QueryArgs()
-	qb := args.Peek(key)
-	if qb != nil {
-		uvBool, _ := strconv.ParseBool(string(qb))
-		return uvBool
-	}
-
-	// Query body
-	var (
-		body = map[string]interface{}{
-			key: nil,
-		}
-		bv interface{}
-	)
-
-	switch strings.ToLower(string(ctx.Request.Header.ContentType())) {
-	case "application/json":
-		json.Unmarshal(ctx.Request.Body(), &body)
-		bv = body[key]
-	case "application/xml":
-		xml.Unmarshal(ctx.Request.Body(), &body)
-		bv = body[key]
-	default:
-		args = ctx.PostArgs()
-		bvb := args.Peek(key)
-		if bvb != nil {
-			bvBool, _ := strconv.ParseBool(string(bvb))
-			return bvBool
-		}
-	}
-
-	bvBool, ok := bv.(bool)
-	if ok {
-		return bvBool
-	}
-
-	return false
+	return HTTPArgsOf(ctx).Bool(key)
 }
 
 /* }}} */
@@ -0,0 +1,270 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file idempotency.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/valyala/fasthttp"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Idempotency settings
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	DefaultIdempotencyTTL     = 24 * time.Hour
+	DefaultIdempotencyLockTTL = 60 * time.Second
+)
+
+// HTTPIdempotencyConfig : Idempotency-Key settings of mutating route, keys scoped by caller
+type HTTPIdempotencyConfig struct {
+	TTL time.Duration
+	// Lock of key in progress, refreshed until handler returned
+	LockTTL  time.Duration
+	Required bool
+	// Caller of request, hash of credential headers (client IP if none) by default
+	Identity func(ctx *fasthttp.RequestCtx) string
+}
+
+// idempotencyRecord : Stored first response of key
+type idempotencyRecord struct {
+	Fingerprint string
+	Done        bool
+	StatusCode  int
+	Headers     map[string][]string
+	Body        []byte
+}
+
+// mwIdempotency : Replay stored response for requests with used Idempotency-Key
+func mwIdempotency(h fasthttp.RequestHandler, conf *HTTPIdempotencyConfig) fasthttp.RequestHandler {
+	ttl := conf.TTL
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+
+	lockTTL := conf.LockTTL
+	if lockTTL <= 0 {
+		lockTTL = DefaultIdempotencyLockTTL
+	}
+
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		key := string(ctx.Request.Header.Peek(IdempotencyKeyHeader))
+		if key == "" {
+			if conf.Required {
+				HTTPEnvelopeError(ctx, Errorf(ErrorCodeBadRequest, http.StatusBadRequest, "Header <%s> required", IdempotencyKeyHeader))
+
+				return
+			}
+
+			h(ctx)
+
+			return
+		}
+
+		r := Redis()
+		if r == nil {
+			Logger().Warn("Idempotency : No redis instance, key ignored")
+			h(ctx)
+
+			return
+		}
+
+		caller := idempotencyCaller(ctx)
+		if conf.Identity != nil {
+			caller = conf.Identity(ctx)
+		}
+
+		scope := sha256.Sum256([]byte(caller))
+		rkey := fmt.Sprintf("%s.idempotency:%s:%s:%s", appName, hex.EncodeToString(scope[:8]), ctx.Path(), key)
+		hash := sha256.New()
+		hash.Write(ctx.Method())
+		hash.Write(ctx.RequestURI())
+		hash.Write(ctx.Request.Body())
+		fp := hex.EncodeToString(hash.Sum(nil))
+
+		pending, _ := msgpack.Marshal(&idempotencyRecord{Fingerprint: fp})
+		locked, err := r.SetNX(context.Background(), rkey, pending, lockTTL).Result()
+		if err != nil {
+			Logger().Errorf("Idempotency : Lock key <%s> failed : %s", key, err.Error())
+			HTTPEnvelopeError(ctx, ErrUnavailable)
+
+			return
+		}
+
+		if !locked {
+			replayIdempotency(ctx, r, rkey, fp)
+
+			return
+		}
+
+		finished := false
+		stop := make(chan struct{})
+		go refreshIdempotencyLock(r, rkey, lockTTL, stop)
+		defer func() {
+			close(stop)
+			if !finished {
+				// Panicked, let client retry
+				r.Del(context.Background(), rkey)
+			}
+		}()
+
+		h(ctx)
+		finished = true
+
+		if ctx.Response.StatusCode() >= http.StatusInternalServerError {
+			// Failed, let client retry
+			r.Del(context.Background(), rkey)
+
+			return
+		}
+
+		record := &idempotencyRecord{
+			Fingerprint: fp,
+			Done:        true,
+			StatusCode:  ctx.Response.StatusCode(),
+			Headers:     make(map[string][]string),
+			Body:        append([]byte(nil), ctx.Response.Body()...),
+		}
+		ctx.Response.Header.VisitAll(func(k, v []byte) {
+			switch string(k) {
+			case "Content-Length", "Date", "Server", "Connection":
+			default:
+				record.Headers[string(k)] = append(record.Headers[string(k)], string(v))
+			}
+		})
+
+		raw, err := msgpack.Marshal(record)
+		if err == nil {
+			err = r.Set(context.Background(), rkey, raw, ttl).Err()
+		}
+
+		if err != nil {
+			Logger().Errorf("Idempotency : Store response of key <%s> failed : %s", key, err.Error())
+		}
+
+		return
+	})
+}
+
+// refreshIdempotencyLock : Keep lock of key alive while handler running, handlers may last longer than lock
+func refreshIdempotencyLock(r *redis.Client, rkey string, lockTTL time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := r.Expire(context.Background(), rkey, lockTTL).Err()
+			if err != nil {
+				Logger().Warnf("Idempotency : Refresh lock <%s> failed : %s", rkey, err.Error())
+			}
+		}
+	}
+}
+
+// idempotencyCaller : Credential headers of request, client IP if anonymous
+func idempotencyCaller(ctx *fasthttp.RequestCtx) string {
+	var b strings.Builder
+	for _, hdr := range httpCacheCredentialHeaders {
+		b.Write(ctx.Request.Header.Peek(hdr))
+		b.WriteString("|")
+	}
+
+	if b.Len() == len(httpCacheCredentialHeaders) {
+		return ctx.RemoteIP().String()
+	}
+
+	return b.String()
+}
+
+// replayIdempotency : Respond stored response, or conflict if still processing
+func replayIdempotency(ctx *fasthttp.RequestCtx, r *redis.Client, rkey, fp string) {
+	raw, err := r.Get(context.Background(), rkey).Bytes()
+	if err != nil {
+		// Expired or released just now
+		HTTPEnvelopeError(ctx, NewError(ErrorCodeConflict, http.StatusConflict, "Request with the same idempotency key is in progress"))
+
+		return
+	}
+
+	record := new(idempotencyRecord)
+	err = msgpack.Unmarshal(raw, record)
+	if err != nil {
+		HTTPEnvelopeError(ctx, ErrInternal)
+
+		return
+	}
+
+	if record.Fingerprint != fp {
+		HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, http.StatusUnprocessableEntity, "Idempotency key reused with different request"))
+
+		return
+	}
+
+	if !record.Done {
+		HTTPEnvelopeError(ctx, NewError(ErrorCodeConflict, http.StatusConflict, "Request with the same idempotency key is in progress"))
+
+		return
+	}
+
+	ctx.Response.Reset()
+	for k, values := range record.Headers {
+		for _, v := range values {
+			ctx.Response.Header.Add(k, v)
+		}
+	}
+
+	ctx.Response.Header.Set(IdempotencyReplayedHeader, "true")
+	ctx.SetStatusCode(record.StatusCode)
+	ctx.SetBody(record.Body)
+
+	return
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,113 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file interceptor.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"strings"
+	"sync"
+)
+
+// Interceptor : Wraps handler function, cross-cutting logic (auth / logging / metrics / tracing) composed once
+type Interceptor func(next UniformHandlerFunc) UniformHandlerFunc
+
+var (
+	interceptorLock       sync.RWMutex
+	globalInterceptors    []Interceptor
+	transportInterceptors = make(map[string][]Interceptor)
+	methodInterceptors    = make(map[string][]Interceptor)
+)
+
+// UseInterceptor : Add interceptors of all handlers, first added runs outermost
+func UseInterceptor(interceptors ...Interceptor) {
+	interceptorLock.Lock()
+	globalInterceptors = appendInterceptors(globalInterceptors, interceptors)
+	interceptorLock.Unlock()
+
+	return
+}
+
+// UseTransportInterceptor : Add interceptors of messages from transport (TransportRPC / TransportTask / TransportNotify / TransportJSONRPC), inside global ones
+func UseTransportInterceptor(transport string, interceptors ...Interceptor) {
+	interceptorLock.Lock()
+	transportInterceptors[transport] = appendInterceptors(transportInterceptors[transport], interceptors)
+	interceptorLock.Unlock()
+
+	return
+}
+
+// UseMethodInterceptor : Add interceptors of handler of method, innermost
+func UseMethodInterceptor(method string, interceptors ...Interceptor) {
+	method = strings.ToLower(method)
+	interceptorLock.Lock()
+	methodInterceptors[method] = appendInterceptors(methodInterceptors[method], interceptors)
+	interceptorLock.Unlock()
+
+	return
+}
+
+func appendInterceptors(chain, interceptors []Interceptor) []Interceptor {
+	for _, i := range interceptors {
+		if i != nil {
+			chain = append(chain, i)
+		}
+	}
+
+	return chain
+}
+
+// intercept : Handler function wrapped by interceptors of all levels, global outermost
+func intercept(method, transport string, hdr UniformHandlerFunc) UniformHandlerFunc {
+	interceptorLock.RLock()
+	defer interceptorLock.RUnlock()
+
+	levels := [][]Interceptor{
+		methodInterceptors[method],
+		transportInterceptors[transport],
+		globalInterceptors,
+	}
+	for _, chain := range levels {
+		for i := len(chain) - 1; i >= 0; i-- {
+			hdr = chain[i](hdr)
+		}
+	}
+
+	return hdr
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,411 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file jsonrpc.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// JSON-RPC 2.0 settings
+const (
+	JSONRPCVersion         = "2.0"
+	JSONRPCSender          = "jsonrpc"
+	DefaultJSONRPCPath     = "/jsonrpc"
+	DefaultJSONRPCMaxBatch = 100
+	// Requests of one batch handled at the same time
+	DefaultJSONRPCConcurrency = 16
+)
+
+// JSON-RPC 2.0 standard error codes
+const (
+	JSONRPCCodeParseError     = -32700
+	JSONRPCCodeInvalidRequest = -32600
+	JSONRPCCodeMethodNotFound = -32601
+	JSONRPCCodeInvalidParams  = -32602
+	JSONRPCCodeInternalError  = -32603
+)
+
+// JSONRPCRequest : JSON-RPC 2.0 request object
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// JSONRPCResponse : JSON-RPC 2.0 response object
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// JSONRPCError : JSON-RPC 2.0 error object
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+var (
+	jsonRPCNullID = json.RawMessage("null")
+	// Methods callable over JSON-RPC, none by default
+	jsonRPCMethods     = make(map[string]bool)
+	jsonRPCMethodsLock sync.RWMutex
+)
+
+// ExposeJSONRPC : Allow handlers of methods to be called over JSON-RPC
+func ExposeJSONRPC(methods ...string) {
+	jsonRPCMethodsLock.Lock()
+	for _, method := range methods {
+		if method != "" {
+			jsonRPCMethods[strings.ToLower(method)] = true
+		}
+	}
+
+	jsonRPCMethodsLock.Unlock()
+
+	return
+}
+
+// jsonRPCConfigMethods : Methods listed in config "http.jsonrpc.methods"
+var jsonRPCConfigMethods = newConfigCache(func() interface{} {
+	methods := make(map[string]bool)
+	for _, method := range Config().GetStringSlice("http.jsonrpc.methods") {
+		methods[strings.ToLower(method)] = true
+	}
+
+	return methods
+})
+
+func jsonRPCExposed(method string) bool {
+	method = strings.ToLower(method)
+	if jsonRPCConfigMethods.get().(map[string]bool)[method] {
+		return true
+	}
+
+	jsonRPCMethodsLock.RLock()
+	defer jsonRPCMethodsLock.RUnlock()
+
+	return jsonRPCMethods[method]
+}
+
+// EnableJSONRPC : Expose handlers as JSON-RPC 2.0 endpoint (POST), route returned for further settings.
+// Only methods listed by ExposeJSONRPC or config "http.jsonrpc.methods" callable, messages sent as JSONRPCSender
+/* {{{ [HTTPServer::EnableJSONRPC] */
+func (s *HTTPServer) EnableJSONRPC(path string) *HTTPRoute {
+	if path == "" {
+		path = DefaultJSONRPCPath
+	}
+
+	route := &HTTPRoute{
+		Name:        "JSONRPC",
+		Description: "JSON-RPC 2.0 endpoint",
+		Method:      "POST",
+		Path:        path,
+		Handler:     jsonRPCHandler,
+	}
+	s.SetRoutes(route)
+
+	return route
+}
+
+/* }}} */
+
+// jsonRPCHandler : Dispatch single or batch request, batch items run in parallel bounded by config "http.jsonrpc.concurrency"
+/* {{{ [jsonRPCHandler] */
+func jsonRPCHandler(ctx *fasthttp.RequestCtx) {
+	c := HTTPContext(ctx)
+	body := bytes.TrimSpace(ctx.Request.Body())
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSONRPC(ctx, jsonRPCErrorResponse(jsonRPCNullID, JSONRPCCodeParseError, "Parse error", nil))
+
+			return
+		}
+
+		maxBatch := Config().GetInt("http.jsonrpc.max_batch")
+		if maxBatch <= 0 {
+			maxBatch = DefaultJSONRPCMaxBatch
+		}
+
+		if len(batch) == 0 || len(batch) > maxBatch {
+			writeJSONRPC(ctx, jsonRPCErrorResponse(jsonRPCNullID, JSONRPCCodeInvalidRequest, "Invalid Request", nil))
+
+			return
+		}
+
+		concurrency := Config().GetInt("http.jsonrpc.concurrency")
+		if concurrency <= 0 {
+			concurrency = DefaultJSONRPCConcurrency
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+		resps := make([]*JSONRPCResponse, len(batch))
+		for i, raw := range batch {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(i int, raw json.RawMessage) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+
+				resps[i] = jsonRPCDispatch(c, raw)
+			}(i, raw)
+		}
+
+		wg.Wait()
+		out := make([]*JSONRPCResponse, 0, len(resps))
+		for _, resp := range resps {
+			if resp != nil {
+				out = append(out, resp)
+			}
+		}
+
+		if len(out) == 0 {
+			// All notifications
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+
+			return
+		}
+
+		writeJSONRPC(ctx, out)
+
+		return
+	}
+
+	resp := jsonRPCDispatch(c, body)
+	if resp == nil {
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+
+		return
+	}
+
+	writeJSONRPC(ctx, resp)
+
+	return
+}
+
+/* }}} */
+
+// jsonRPCDispatch : Run one request, nil returned for notification
+/* {{{ [jsonRPCDispatch] */
+func jsonRPCDispatch(ctx context.Context, raw json.RawMessage) *JSONRPCResponse {
+	req := new(JSONRPCRequest)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if err := dec.Decode(req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return jsonRPCErrorResponse(jsonRPCNullID, JSONRPCCodeParseError, "Parse error", nil)
+		}
+
+		return jsonRPCErrorResponse(jsonRPCNullID, JSONRPCCodeInvalidRequest, "Invalid Request", nil)
+	}
+
+	id := req.ID
+	notification := len(id) == 0
+	if notification {
+		id = jsonRPCNullID
+	} else if !jsonRPCValidID(id) {
+		return jsonRPCErrorResponse(jsonRPCNullID, JSONRPCCodeInvalidRequest, "Invalid Request", nil)
+	}
+
+	if req.JSONRPC != JSONRPCVersion || req.Method == "" {
+		return jsonRPCErrorResponse(id, JSONRPCCodeInvalidRequest, "Invalid Request", nil)
+	}
+
+	reply := func(resp *JSONRPCResponse) *JSONRPCResponse {
+		if notification {
+			return nil
+		}
+
+		return resp
+	}
+
+	h := GetHandler(req.Method)
+	if h == nil || !jsonRPCExposed(req.Method) {
+		return reply(jsonRPCErrorResponse(id, JSONRPCCodeMethodNotFound, "Method not found", nil))
+	}
+
+	var params interface{}
+	if len(req.Params) > 0 {
+		pdec := json.NewDecoder(bytes.NewReader(req.Params))
+		pdec.UseNumber()
+		if err := pdec.Decode(&params); err != nil {
+			return reply(jsonRPCErrorResponse(id, JSONRPCCodeInvalidParams, "Invalid params", nil))
+		}
+
+		switch params.(type) {
+		case map[string]interface{}, []interface{}:
+			params = normalizeArgValue(params)
+		default:
+			// By-position or by-name only
+			return reply(jsonRPCErrorResponse(id, JSONRPCCodeInvalidParams, "Invalid params", nil))
+		}
+	}
+
+	msg := NewUniformMessage(params, false).WithContext(ctx)
+	msg.Sender = JSONRPCSender
+	msg.Reciever = _msgTarget(App().Name)
+	msg.Method = req.Method
+	if Config().GetBool("http.server.access_log") {
+		Logger().Debugf("JSON-RPC Access : <%s>", req.Method)
+	}
+
+	var ret *ResultMessage
+	err := acceptMessage(h, msg, TransportJSONRPC)
+	if err != nil {
+		Logger().Errorf("JSON-RPC : Message <%s> rejected : %s", msg.ID, err.Error())
+	} else {
+		ret, err = h.call(msg)
+	}
+
+	if ret == nil {
+		ret = NewResultMessage(nil, false)
+	}
+
+	if err != nil {
+		Logger().Error(err)
+		if ret.Code == RPCCodeOK {
+			ret.SetError(err)
+		}
+	}
+
+	if notification {
+		return nil
+	}
+
+	if err = ret.Err(); err != nil {
+		e := AsError(err)
+		code := e.Code
+		switch code {
+		case ErrorCodeInternal, ErrorCodePanic:
+			code = JSONRPCCodeInternalError
+		case ErrorCodeBadRequest:
+			code = JSONRPCCodeInvalidParams
+		}
+
+		data := map[string]interface{}{
+			"http_status": e.HTTPStatus,
+		}
+		if e.ErrorPrompt != "" {
+			data["error_prompt"] = e.ErrorPrompt
+		}
+
+		if len(e.Details) > 0 {
+			data["details"] = e.Details
+		}
+
+		return jsonRPCErrorResponse(id, code, e.Message, data)
+	}
+
+	resp := &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		ID:      id,
+	}
+	if ret.Length() > 0 {
+		var result interface{}
+		if err = ret.Unmarshal(&result); err != nil {
+			return jsonRPCErrorResponse(id, JSONRPCCodeInternalError, "Internal error", nil)
+		}
+
+		resp.Result = result
+	}
+
+	if resp.Result == nil {
+		// Result member is required on success
+		resp.Result = json.RawMessage("null")
+	}
+
+	return resp
+}
+
+/* }}} */
+
+// jsonRPCValidID : ID must be string, number or null
+func jsonRPCValidID(id json.RawMessage) bool {
+	var v interface{}
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+
+	switch v.(type) {
+	case string, float64, nil:
+		return true
+	}
+
+	return false
+}
+
+func jsonRPCErrorResponse(id json.RawMessage, code int, message string, data interface{}) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		Error: &JSONRPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+		ID: id,
+	}
+}
+
+func writeJSONRPC(ctx *fasthttp.RequestCtx, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		Logger().Errorf("JSON-RPC : Encode response failed : %s", err.Error())
+		body, _ = json.Marshal(jsonRPCErrorResponse(jsonRPCNullID, JSONRPCCodeInternalError, "Internal error", nil))
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+
+	return
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
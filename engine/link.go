@@ -0,0 +1,362 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file link.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Link relations
+const (
+	LinkRelSelf  = "self"
+	LinkRelFirst = "first"
+	LinkRelPrev  = "prev"
+	LinkRelNext  = "next"
+	LinkRelLast  = "last"
+)
+
+var (
+	// Existing clients read "linkes", typed links opt-in
+	httpLegacyLinks = true
+)
+
+// SetHTTPLegacyLinks : Serialize envelope links as list of href under the old "linkes" key (default),
+// typed links under "links" if false
+func SetHTTPLegacyLinks(legacy bool) {
+	httpLegacyLinks = legacy
+}
+
+// GetHTTPLegacyLinks : Get legacy links flag
+func GetHTTPLegacyLinks() bool {
+	return httpLegacyLinks
+}
+
+// HTTPLink : Hypermedia link of envelope
+type HTTPLink struct {
+	Rel    string `json:"rel" xml:"rel,attr"`
+	Href   string `json:"href" xml:"href,attr"`
+	Method string `json:"method,omitempty" xml:"method,attr,omitempty"`
+}
+
+// NewHTTPLink : Create link
+func NewHTTPLink(rel, href, method string) *HTTPLink {
+	return &HTTPLink{
+		Rel:    rel,
+		Href:   href,
+		Method: strings.ToUpper(method),
+	}
+}
+
+/* {{{ [Reverse routing] */
+
+// URL : Build URL of named route, params are key / value pairs filling path parameters, the rest go to query string
+/* {{{ [HTTPServer::URL] */
+func (s *HTTPServer) URL(name string, params ...interface{}) (string, error) {
+	route := s.namedRoute(name)
+	if route == nil {
+		return "", fmt.Errorf("Route <%s> not found", name)
+	}
+
+	return buildRouteURL(route.Path, params)
+}
+
+/* }}} */
+
+// Link : Build link of named route with method of route
+/* {{{ [HTTPServer::Link] */
+func (s *HTTPServer) Link(rel, name string, params ...interface{}) (*HTTPLink, error) {
+	route := s.namedRoute(name)
+	if route == nil {
+		return nil, fmt.Errorf("Route <%s> not found", name)
+	}
+
+	href, err := buildRouteURL(route.Path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	method := route.Method
+	if method == "" {
+		method = fasthttp.MethodGet
+	}
+
+	return NewHTTPLink(rel, href, method), nil
+}
+
+/* }}} */
+
+// HTTPURL : Build URL of named route on HTTP server of application
+func HTTPURL(name string, params ...interface{}) (string, error) {
+	if App() == nil || App().http == nil {
+		return "", fmt.Errorf("No HTTP server")
+	}
+
+	return App().http.URL(name, params...)
+}
+
+// HTTPLinkTo : Build link of named route on HTTP server of application
+func HTTPLinkTo(rel, name string, params ...interface{}) (*HTTPLink, error) {
+	if App() == nil || App().http == nil {
+		return nil, fmt.Errorf("No HTTP server")
+	}
+
+	return App().http.Link(rel, name, params...)
+}
+
+func (s *HTTPServer) namedRoute(name string) *HTTPRoute {
+	for _, route := range s.routes {
+		if route.Name == name {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// buildRouteURL : Fill path template ({name}, {name?}, {name:regex}, {name:*}) and append query string
+/* {{{ [buildRouteURL] */
+func buildRouteURL(path string, params []interface{}) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("Odd number of route parameters")
+	}
+
+	var keys []string
+	values := make(map[string]string)
+	for i := 0; i < len(params); i += 2 {
+		key := fmt.Sprint(params[i])
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	used := make(map[string]bool)
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '{' {
+			b.WriteByte(path[i])
+
+			continue
+		}
+
+		// Braces of regex nested
+		depth := 0
+		end := -1
+		for j := i; j < len(path); j++ {
+			if path[j] == '{' {
+				depth++
+			} else if path[j] == '}' {
+				depth--
+				if depth == 0 {
+					end = j
+
+					break
+				}
+			}
+		}
+
+		if end < 0 {
+			return "", fmt.Errorf("Invalid route path <%s>", path)
+		}
+
+		spec := path[i+1 : end]
+		i = end
+		name, pattern := spec, ""
+		if idx := strings.Index(spec, ":"); idx >= 0 {
+			name, pattern = spec[:idx], spec[idx+1:]
+		}
+
+		optional := strings.HasSuffix(name, "?")
+		name = strings.TrimSuffix(name, "?")
+		v, ok := values[name]
+		if !ok {
+			if optional {
+				continue
+			}
+
+			return "", fmt.Errorf("Route parameter <%s> missing", name)
+		}
+
+		used[name] = true
+		if pattern == "*" {
+			parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+			for k, part := range parts {
+				parts[k] = url.PathEscape(part)
+			}
+
+			b.WriteString(strings.Join(parts, "/"))
+		} else {
+			b.WriteString(url.PathEscape(v))
+		}
+	}
+
+	u := b.String()
+	for strings.Contains(u, "//") {
+		u = strings.Replace(u, "//", "/", -1)
+	}
+
+	if len(u) > 1 && strings.HasSuffix(u, "/") && !strings.HasSuffix(path, "/") {
+		u = strings.TrimSuffix(u, "/")
+	}
+
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+	for _, key := range keys {
+		if !used[key] {
+			args.Add(key, values[key])
+		}
+	}
+
+	if args.Len() > 0 {
+		u += "?" + args.String()
+	}
+
+	return u, nil
+}
+
+/* }}} */
+
+/* }}} */
+
+/* {{{ [Envelope links] */
+
+// AddLink : Append link to envelope
+func (e *HTTPResponseEnvelope) AddLink(rel, href, method string) *HTTPResponseEnvelope {
+	e.Links = append(e.Links, NewHTTPLink(rel, href, method))
+
+	return e
+}
+
+// Link : Get link by relation, nil if not exists
+func (e *HTTPResponseEnvelope) Link(rel string) *HTTPLink {
+	for _, l := range e.Links {
+		if l.Rel == rel {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// paginationLinks : Fill first / prev / next / last links from pagination and request URI
+/* {{{ [HTTPResponseEnvelope::paginationLinks] */
+func (e *HTTPResponseEnvelope) paginationLinks(ctx *fasthttp.RequestCtx) {
+	p := e.Pagination
+	if p == nil || p.All || p.EntriesPerPage <= 0 {
+		return
+	}
+
+	pages := int((p.TotalEntries + int64(p.EntriesPerPage) - 1) / int64(p.EntriesPerPage))
+	if pages < 1 {
+		pages = 1
+	}
+
+	current := p.Current
+	if current < 1 {
+		current = 1
+	}
+
+	pageURL := func(page int) string {
+		args := fasthttp.AcquireArgs()
+		defer fasthttp.ReleaseArgs(args)
+		ctx.QueryArgs().CopyTo(args)
+		args.Del(paginationStart)
+		args.Set(paginationPage, strconv.Itoa(page))
+		args.Set(paginationPerPage, strconv.Itoa(p.EntriesPerPage))
+
+		return string(ctx.Path()) + "?" + args.String()
+	}
+
+	add := func(rel string, page int) {
+		if e.Link(rel) == nil {
+			e.AddLink(rel, pageURL(page), fasthttp.MethodGet)
+		}
+	}
+
+	add(LinkRelFirst, 1)
+	if current > 1 {
+		add(LinkRelPrev, current-1)
+	}
+
+	if current < pages {
+		add(LinkRelNext, current+1)
+	}
+
+	add(LinkRelLast, pages)
+
+	return
+}
+
+/* }}} */
+
+// MarshalJSON : Links as list of href under "linkes" in legacy mode (default), or typed under "links"
+/* {{{ [HTTPResponseEnvelope::MarshalJSON] */
+func (e *HTTPResponseEnvelope) MarshalJSON() ([]byte, error) {
+	type envelope HTTPResponseEnvelope
+	if !httpLegacyLinks {
+		return json.Marshal((*envelope)(e))
+	}
+
+	var hrefs []string
+	for _, l := range e.Links {
+		hrefs = append(hrefs, l.Href)
+	}
+
+	return json.Marshal(&struct {
+		*envelope
+		Links       []*HTTPLink `json:"links,omitempty"`
+		LegacyLinks []string    `json:"linkes,omitempty"`
+	}{
+		envelope:    (*envelope)(e),
+		LegacyLinks: hrefs,
+	})
+}
+
+/* }}} */
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
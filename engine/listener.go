@@ -0,0 +1,354 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file listener.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/reuseport"
+)
+
+const (
+	// DefaultUnixSocketMode : File mode of unix socket if not given
+	DefaultUnixSocketMode os.FileMode = 0660
+)
+
+// HTTPListener : Listen address of HTTP server
+type HTTPListener struct {
+	// Network : tcp4 (default), tcp6, tcp or unix
+	Network string
+	// Addr : host:port, or socket file path for unix
+	Addr string
+	// FD : Pre-opened listener passed by file descriptor (socket activation / graceful restart), Network and Addr ignored
+	FD uintptr
+	// Mode : File mode of unix socket
+	Mode os.FileMode
+	// ReusePort : Open Shards listeners on the same address with SO_REUSEPORT, kernel balances connections among them
+	ReusePort bool
+	// Shards : Number of SO_REUSEPORT listeners, number of CPUs if 0
+	Shards int
+	// TLS : Serve with TLS configuration of server
+	TLS bool
+	// Internal : Internal listener, routes marked as internal are only served here
+	Internal bool
+}
+
+// AddListener : Listen on more addresses, the address given to NewHTTPServer is the first one
+/* {{{ [HTTPServer::AddListener] */
+func (s *HTTPServer) AddListener(listeners ...*HTTPListener) {
+	s.listeners = append(s.listeners, listeners...)
+}
+
+/* }}} */
+
+// Addrs : Bound addresses of opened listeners
+/* {{{ [HTTPServer::Addrs] */
+func (s *HTTPServer) Addrs() []net.Addr {
+	var addrs []net.Addr
+	for _, ln := range s.opened {
+		addrs = append(addrs, ln.Addr())
+	}
+
+	return addrs
+}
+
+/* }}} */
+
+// openListeners : Open all listeners of server
+/* {{{ [HTTPServer::openListeners] */
+func (s *HTTPServer) openListeners() ([]net.Listener, error) {
+	var (
+		loader *TLSLoader
+		opened []net.Listener
+	)
+
+	listeners := s.listeners
+	if s.addr != "" {
+		listeners = append([]*HTTPListener{{Addr: s.addr, TLS: s.tlsConf != nil}}, listeners...)
+	}
+
+	closeAll := func() {
+		for _, ln := range opened {
+			ln.Close()
+		}
+	}
+
+	for _, l := range listeners {
+		lns, err := l.open()
+		if err != nil {
+			closeAll()
+
+			return nil, err
+		}
+
+		for _, ln := range lns {
+			if l.TLS {
+				if s.tlsConf == nil {
+					ln.Close()
+					closeAll()
+
+					return nil, fmt.Errorf("TLS listener <%s> without TLS configuration", l.Addr)
+				}
+
+				if loader == nil {
+					loader, err = NewTLSLoader(s.tlsConf)
+					if err != nil {
+						ln.Close()
+						closeAll()
+
+						return nil, err
+					}
+				}
+
+				ln = tls.NewListener(ln, loader.ServerConfig())
+			}
+
+			if l.Internal {
+				ln = &httpInternalListener{Listener: ln}
+			}
+
+			opened = append(opened, ln)
+		}
+	}
+
+	return opened, nil
+}
+
+/* }}} */
+
+// open : Open listener(s) by settings
+/* {{{ [HTTPListener::open] */
+func (l *HTTPListener) open() ([]net.Listener, error) {
+	if l.FD > 0 {
+		f := os.NewFile(l.FD, fmt.Sprintf("listener.%d", l.FD))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Listener from file descriptor %d failed : %s", l.FD, err.Error())
+		}
+
+		return []net.Listener{ln}, nil
+	}
+
+	network := strings.ToLower(l.Network)
+	if network == "" {
+		network = "tcp4"
+	}
+
+	if network == "unix" {
+		ln, err := listenUnix(l.Addr, l.Mode)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{ln}, nil
+	}
+
+	if !l.ReusePort {
+		ln, err := net.Listen(network, l.Addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{ln}, nil
+	}
+
+	if network == "tcp" {
+		// Reuseport supports tcp4 and tcp6 only
+		network = "tcp4"
+	}
+
+	shards := l.Shards
+	if shards <= 0 {
+		shards = runtime.NumCPU()
+	}
+
+	var lns []net.Listener
+	addr := l.Addr
+	for i := 0; i < shards; i++ {
+		ln, err := reuseport.Listen(network, addr)
+		if err != nil {
+			for _, opened := range lns {
+				opened.Close()
+			}
+
+			return nil, err
+		}
+
+		// Port 0 : shards share the port chosen by the first one
+		addr = ln.Addr().String()
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+/* }}} */
+
+/* {{{ [Unix socket listener] */
+
+// unixSocketListener : Unix socket moved into place after created, removes socket file on close
+type unixSocketListener struct {
+	net.Listener
+	path string
+}
+
+// listenUnix : Create socket in a private (0700) directory, set mode, then rename to path, so never reachable with looser mode
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if mode == 0 {
+		mode = DefaultUnixSocketMode
+	}
+
+	// Stale socket file left by previous process is replaced by rename, anything else is kept
+	if st, err := os.Lstat(path); err == nil && st.Mode()&os.ModeSocket == 0 {
+		return nil, fmt.Errorf("Unix socket path <%s> exists and is not a socket", path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, filepath.Base(path))
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, mode); err != nil {
+		ln.Close()
+
+		return nil, fmt.Errorf("Chmod %#o for unix socket <%s> failed : %s", mode, path, err.Error())
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		ln.Close()
+
+		return nil, err
+	}
+
+	return &unixSocketListener{Listener: ln, path: path}, nil
+}
+
+// Addr : Final socket path instead of the temporary one
+func (l *unixSocketListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close : Close listener and remove socket file
+func (l *unixSocketListener) Close() error {
+	err := l.Listener.Close()
+	os.Remove(l.path)
+
+	return err
+}
+
+/* }}} */
+
+/* {{{ [Internal listener] */
+
+// httpInternalListener : Mark accepted connections as internal
+type httpInternalListener struct {
+	net.Listener
+}
+
+type httpInternalConn struct {
+	net.Conn
+}
+
+// httpInternalTLSConn : Keeps TLS state visible to fasthttp
+type httpInternalTLSConn struct {
+	*tls.Conn
+}
+
+// Accept : Wrap connection
+func (l *httpInternalListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if tc, ok := c.(*tls.Conn); ok {
+		return &httpInternalTLSConn{Conn: tc}, nil
+	}
+
+	return &httpInternalConn{Conn: c}, nil
+}
+
+// HTTPIsInternal : Request comes from internal listener
+func HTTPIsInternal(ctx *fasthttp.RequestCtx) bool {
+	switch ctx.Conn().(type) {
+	case *httpInternalConn, *httpInternalTLSConn:
+		return true
+	}
+
+	return false
+}
+
+// mwInternal : Hide route from public listeners
+func mwInternal(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		if !HTTPIsInternal(ctx) {
+			HTTPEnvelopeError(ctx, ErrNotFound)
+
+			return
+		}
+
+		h(ctx)
+
+		return
+	})
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
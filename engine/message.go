@@ -32,6 +32,8 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -46,6 +48,8 @@ import (
 const (
 	TaskTopicPrefix   = "_.task_"
 	NotifyTopicPrefix = "_.notify_"
+	// Rejected tasks kept for inspection
+	TaskDeadTopicPrefix = "_.task_dead_"
 )
 
 // DistinguishBranch
@@ -71,7 +75,28 @@ type UniformMessage struct {
 	Method   string
 	Compress bool
 	Time     time.Time
-	Data     []byte
+	Deadline time.Time
+	// Time left before deadline when sent
+	Remaining time.Duration
+	// Retry attempt of call
+	Attempt   int
+	Operation string
+	// HMAC signature by key of sender
+	KeyID     string
+	Signature []byte
+	Data      []byte
+	ctx       context.Context
+	// Key of consistent hashing balancer
+	balanceKey string
+	options    *CallOptions
+	// Instance pinned by CallAll, balancer bypassed
+	instance *ServiceInstance
+	// Stream of streaming call on server side
+	stream *ResultStream
+	// Transport message recieved from
+	transport string
+	// Operation reported as finished by handler
+	operationDone bool
 }
 
 // NewUniformMessage : Create new uniform message
@@ -99,8 +124,13 @@ func NewUniformMessage(data interface{}, compress bool) *UniformMessage {
 	return msg
 }
 
-// Encode : Stringify
+// Encode : Stringify, signed by key of sender if configured
 func (msg *UniformMessage) Encode() ([]byte, error) {
+	err := msg.sign()
+	if err != nil {
+		return nil, err
+	}
+
 	return msgpack.Marshal(msg)
 }
 
@@ -128,6 +158,37 @@ func (msg *UniformMessage) Unmarshal(v interface{}) error {
 	return msgpack.Unmarshal(raw, v)
 }
 
+// WithContext : Bind context, its deadline travels with message to remote handler
+func (msg *UniformMessage) WithContext(ctx context.Context) *UniformMessage {
+	msg.ctx = ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.Deadline = deadline
+	}
+
+	return msg
+}
+
+// WithBalanceKey : Route calls with same key to same instance (consistent hash balancer)
+func (msg *UniformMessage) WithBalanceKey(key string) *UniformMessage {
+	msg.balanceKey = key
+
+	return msg
+}
+
+// Transport : Transport message recieved from, empty if not recieved
+func (msg *UniformMessage) Transport() string {
+	return msg.transport
+}
+
+// Context : Context of message, cancelled when caller gone or deadline exceeded
+func (msg *UniformMessage) Context() context.Context {
+	if msg.ctx != nil {
+		return msg.ctx
+	}
+
+	return context.Background()
+}
+
 // Length : Data size
 func (msg *UniformMessage) Length() int {
 	if msg.Compress {
@@ -153,23 +214,58 @@ func _msgTarget(target string) string {
 	return target
 }
 
-// Call : Synchronously RPC via HTTP2
+// Call : Synchronously RPC via HTTP2, reciever resolved by service registry if set.
+// Idempotent calls failed in transport (or unavailable) retried with backoff, within deadline and retry budget of target
 func (msg *UniformMessage) Call(reciever, method string) (*ResultMessage, error) {
 	msg.Reciever = _msgTarget(reciever)
 	msg.Method = method
 	msg.Sender = App().Name
-	payload, err := msg.Encode()
-	if err != nil {
-		// Encode failed
-		return nil, err
+	opts := callOptions(msg.Reciever, method, msg.options)
+	if opts.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(msg.Context(), opts.Timeout)
+		defer cancel()
+		msg.WithContext(ctx)
+	}
+
+	var (
+		r   *ResultMessage
+		err error
+	)
+
+	ctx := msg.Context()
+	budget := getRetryBudget(msg.Reciever)
+	for attempt := 0; ; attempt++ {
+		msg.Attempt = attempt
+		ar, failed, aerr := msg.callOnce(ctx)
+		if attempt > 0 && errors.Is(aerr, errCircuitOpen) {
+			// Circuit opened by attempts before, their error returned
+			break
+		}
+
+		r, err = ar, aerr
+		retryable := failed || (r != nil && r.Code == ErrorCodeUnavailable)
+		budget.report(retryable)
+		if !retryable || !opts.Idempotent || attempt >= opts.Retries || ctx.Err() != nil {
+			break
+		}
+
+		if !budget.allow() {
+			Logger().Warnf("RPC call to <%s>:[%s] not retried, retry budget exhausted", reciever, method)
+
+			break
+		}
+
+		if !opts.backoff(ctx, attempt) {
+			break
+		}
+
+		Logger().Debugf("RPC call to <%s>:[%s] retry %d", reciever, method, attempt+1)
 	}
 
-	client := NewRPCClient(msg.Reciever)
-	r, err := client.Call(payload)
 	if err != nil {
 		Logger().Errorf("RPC call to <%s>:[%s] failed : %s", reciever, method, err.Error())
 
-		return nil, err
+		return r, err
 	}
 
 	Logger().Debugf("RPC call to <%s>:[%s] finished", reciever, method)
@@ -180,6 +276,69 @@ func (msg *UniformMessage) Call(reciever, method string) (*ResultMessage, error)
 	return nil, fmt.Errorf("Empty RPC response body")
 }
 
+// callOnce : One attempt of call, failed if no result (transport failure)
+func (msg *UniformMessage) callOnce(ctx context.Context) (*ResultMessage, bool, error) {
+	inst, addr := msg.instance, ""
+	viaNats := inst == nil && rpcTargetTransport(msg.Reciever) == RPCTransportNats
+	if inst != nil {
+		addr = inst.Address()
+	} else if viaNats {
+		if _, ok := ctx.Deadline(); !ok {
+			// Request never ends without deadline
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, rpcClientDuration("rpc.nats.timeout", DefaultRPCNatsTimeout))
+			defer cancel()
+		}
+	} else {
+		var err error
+		inst, addr, err = resolveRPCTarget(ctx, msg.Reciever, msg.balanceKey)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	breaker := getBreaker(msg.Reciever, addr)
+	probe, ok := breaker.allow()
+	if !ok {
+		return nil, false, circuitOpenError(breakerKey(msg.Reciever, addr))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		// Remaining time, free from clock skew between hosts
+		msg.Remaining = time.Until(deadline)
+		if msg.Remaining <= 0 {
+			breaker.release(probe)
+
+			return nil, true, rpcContextError(ctx, context.DeadlineExceeded)
+		}
+	}
+
+	payload, err := msg.Encode()
+	if err != nil {
+		// Encode failed
+		breaker.release(probe)
+
+		return nil, false, err
+	}
+
+	if viaNats {
+		r, err := natsRPCRequest(ctx, msg.Reciever, payload)
+		failed := r == nil && err != nil
+		breaker.finish(ctx, probe, failed)
+
+		return r, failed, err
+	}
+
+	r, err := GetRPCClient(addr).CallContext(ctx, payload)
+	failed := r == nil && err != nil
+	breaker.finish(ctx, probe, failed)
+	if inst != nil {
+		reportInstance(msg.Reciever, inst, failed)
+	}
+
+	return r, failed, err
+}
+
 // Task : Asynchronously queue via NSQ
 func (msg *UniformMessage) Task(target, method string) error {
 	msg.Reciever = _msgTarget(target)
@@ -213,7 +372,7 @@ func (msg *UniformMessage) Notify(target, method string) error {
 	msg.Sender = App().Name
 	payload, err := msg.Encode()
 	if err != nil {
-		return nil
+		return err
 	}
 
 	client := App().nats
@@ -234,13 +393,15 @@ func (msg *UniformMessage) Notify(target, method string) error {
 
 // ResultMessage : Result
 type ResultMessage struct {
-	ID         string
-	Code       int
-	HTTPStatus int
-	Message    string
-	Compress   bool
-	Time       time.Time
-	Data       []byte
+	ID          string
+	Code        int
+	HTTPStatus  int
+	Message     string
+	ErrorPrompt string
+	Details     map[string]interface{}
+	Compress    bool
+	Time        time.Time
+	Data        []byte
 }
 
 // NewResultMessage : Create result message
@@ -264,6 +425,48 @@ func NewResultMessage(data interface{}, compress bool) *ResultMessage {
 	return msg
 }
 
+// NewErrorResultMessage : Create result message from error
+func NewErrorResultMessage(err error) *ResultMessage {
+	msg := NewResultMessage(nil, false)
+	msg.SetError(err)
+
+	return msg
+}
+
+// SetError : Fill code, status and message from error
+func (msg *ResultMessage) SetError(err error) {
+	e := AsError(err)
+	if e == nil {
+		return
+	}
+
+	msg.Code = e.Code
+	msg.HTTPStatus = e.HTTPStatus
+	msg.Message = e.Message
+	msg.ErrorPrompt = e.ErrorPrompt
+	msg.Details = e.Details
+}
+
+// Err : Reconstruct error from result, nil if succeed
+func (msg *ResultMessage) Err() error {
+	if msg.Code == RPCCodeOK && msg.HTTPStatus < http.StatusBadRequest {
+		return nil
+	}
+
+	code := msg.Code
+	if code == RPCCodeOK {
+		code = ErrorCodeInternal
+	}
+
+	return &Error{
+		Code:        code,
+		HTTPStatus:  msg.HTTPStatus,
+		Message:     msg.Message,
+		ErrorPrompt: msg.ErrorPrompt,
+		Details:     msg.Details,
+	}
+}
+
 // Encode : Stringify
 func (msg *ResultMessage) Encode() ([]byte, error) {
 	return msgpack.Marshal(msg)
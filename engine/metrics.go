@@ -57,11 +57,9 @@ const (
 
 // MetricsIns : Instance (HTTP server) of prometheus exporter
 type MetricsIns struct {
-	addr        string
-	tls         bool
-	sslCertFile string
-	sslKeyFile  string
-	server      *http.Server
+	addr    string
+	tlsConf *TLSConfig
+	server  *http.Server
 
 	list       map[string]*Metric
 	counters   map[string]prometheus.Counter
@@ -102,9 +100,10 @@ func NewMetrics(addr string) *MetricsIns {
 /* {{{ [MetricsIns::SetSSL] */
 func (metrics *MetricsIns) SetSSL(sslCertFile, sslKeyFile string) {
 	if sslCertFile != "" && sslKeyFile != "" {
-		metrics.tls = true
-		metrics.sslCertFile = sslCertFile
-		metrics.sslKeyFile = sslKeyFile
+		metrics.SetTLS(&TLSConfig{
+			CertFile: sslCertFile,
+			KeyFile:  sslKeyFile,
+		})
 	}
 
 	return
@@ -112,18 +111,41 @@ func (metrics *MetricsIns) SetSSL(sslCertFile, sslKeyFile string) {
 
 /* }}} */
 
+// SetTLS : Set TLS (mutual TLS if CA bundle given) configuration for metrics node
+/* {{{ [MetricsIns::SetTLS] */
+func (metrics *MetricsIns) SetTLS(conf *TLSConfig) {
+	metrics.tlsConf = conf
+
+	return
+}
+
+/* }}} */
+
 // Startup : Start and serve
 /* {{{ [MetricsIns::Startup] */
 func (metrics *MetricsIns) Startup(logger *logrus.Entry) {
 	mux := http.NewServeMux()
 	mux.Handle(MetricsRoute, promhttp.Handler())
+	if metrics.tlsConf == nil {
+		metrics.tlsConf = TLSConfigFromConfig("metrics.tls")
+	}
+
 	go func() {
 		var failed error
 		metrics.server.Handler = mux
-		if metrics.tls {
+		if metrics.tlsConf != nil {
 			// HTTPS
+			var loader *TLSLoader
+			loader, failed = NewTLSLoader(metrics.tlsConf)
+			if failed != nil {
+				logger.Errorf("Prometheus exporter node TLS failed : %s", failed.Error())
+
+				return
+			}
+
+			metrics.server.TLSConfig = loader.ServerConfig()
 			logger.Printf("Prometheus exporter node initialized at [%s] with SSL", metrics.addr)
-			failed = metrics.server.ListenAndServeTLS(metrics.sslCertFile, metrics.sslKeyFile)
+			failed = metrics.server.ListenAndServeTLS("", "")
 		} else {
 			logger.Printf("Prometheus exporter node initialized at [%s]", metrics.addr)
 			failed = metrics.server.ListenAndServe()
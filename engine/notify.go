@@ -63,6 +63,13 @@ func _notifyNatsConsumerHandler(m *nats.Msg) {
 		return
 	}
 
+	err = acceptMessage(h, msg, TransportNotify)
+	if err != nil {
+		Logger().Errorf("Notify : Message <%s> rejected : %s", msg.ID, err.Error())
+
+		return
+	}
+
 	runHandler(h, msg)
 
 	return
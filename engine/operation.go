@@ -0,0 +1,526 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file operation.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/valyala/fasthttp"
+)
+
+// Operation settings
+const (
+	OperationKeyPrefix       = "_.operation_"
+	DefaultOperationPath     = "/_operations"
+	DefaultOperationTTL      = 24 * time.Hour
+	OperationKeepAlive       = 15 * time.Second
+	OperationRouteName       = "Operation"
+	OperationEventsRouteName = "OperationEvents"
+	OperationUpdateRetries   = 8
+)
+
+// Operation status
+const (
+	OperationStatusPending   = "pending"
+	OperationStatusRunning   = "running"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+)
+
+// Operation : State of long-running task, stored in redis
+type Operation struct {
+	ID        string          `json:"id" xml:"id"`
+	Status    string          `json:"status" xml:"status"`
+	Progress  float64         `json:"progress" xml:"progress"`
+	Message   string          `json:"message,omitempty" xml:"message,omitempty"`
+	Result    interface{}     `json:"result,omitempty" xml:"-"`
+	Error     *OperationError `json:"error,omitempty" xml:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" xml:"updated_at"`
+	// Hash of caller identity created operation, empty if not created by HTTP request
+	owner string
+}
+
+// operationRecord : Stored form of operation, owner kept out of responses
+type operationRecord struct {
+	*Operation
+	Owner string `json:"owner,omitempty"`
+}
+
+var (
+	operationIdentity func(ctx *fasthttp.RequestCtx) string
+)
+
+// SetOperationIdentity : Set caller identity of operation requests, hash of credential headers (client IP if none) by default
+func SetOperationIdentity(identity func(ctx *fasthttp.RequestCtx) string) {
+	operationIdentity = identity
+}
+
+// operationOwner : Hash of caller identity, only the creator reads operation
+func operationOwner(ctx *fasthttp.RequestCtx) string {
+	caller := idempotencyCaller(ctx)
+	if operationIdentity != nil {
+		caller = operationIdentity(ctx)
+	}
+
+	sum := sha256.Sum256([]byte(caller))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// OperationError : Error of failed operation
+type OperationError struct {
+	Code        int    `json:"code" xml:"code"`
+	HTTPStatus  int    `json:"http_status" xml:"http_status"`
+	Message     string `json:"message" xml:"message"`
+	ErrorPrompt string `json:"error_prompt,omitempty" xml:"error_prompt,omitempty"`
+}
+
+// Finished : Operation succeeded or failed
+func (op *Operation) Finished() bool {
+	return op.Status == OperationStatusSucceeded || op.Status == OperationStatusFailed
+}
+
+func operationKey(id string) string {
+	return OperationKeyPrefix + id
+}
+
+func operationTTL() time.Duration {
+	ttl := Config().GetDuration("operation.ttl")
+	if ttl <= 0 {
+		ttl = DefaultOperationTTL
+	}
+
+	return ttl
+}
+
+func operationRedis() (*redis.Client, error) {
+	r := Redis()
+	if r == nil {
+		return nil, NewError(ErrUnavailable.Code, ErrUnavailable.HTTPStatus, "No redis instance for operations")
+	}
+
+	return r, nil
+}
+
+// GetOperation : Load operation by ID
+/* {{{ [GetOperation] */
+func GetOperation(ctx context.Context, id string) (*Operation, error) {
+	r, err := operationRedis()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadOperation(ctx, r, id)
+}
+
+/* }}} */
+
+// loadOperation : Read operation by client or transaction
+func loadOperation(ctx context.Context, r interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+}, id string) (*Operation, error) {
+	raw, err := r.Get(ctx, operationKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, Errorf(ErrorCodeNotFound, http.StatusNotFound, "Operation <%s> not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	op := new(Operation)
+	record := &operationRecord{Operation: op}
+	err = json.Unmarshal(raw, record)
+	if err != nil {
+		return nil, err
+	}
+
+	op.owner = record.Owner
+
+	return op, nil
+}
+
+// encode : Stored form of operation, update time refreshed
+func (op *Operation) encode() ([]byte, error) {
+	op.UpdatedAt = time.Now()
+
+	return json.Marshal(&operationRecord{Operation: op, Owner: op.owner})
+}
+
+// save : Store operation and publish to subscribers
+/* {{{ [Operation::save] */
+func (op *Operation) save(ctx context.Context) error {
+	r, err := operationRedis()
+	if err != nil {
+		return err
+	}
+
+	raw, err := op.encode()
+	if err != nil {
+		return err
+	}
+
+	key := operationKey(op.ID)
+	err = r.Set(ctx, key, raw, operationTTL()).Err()
+	if err != nil {
+		return err
+	}
+
+	return r.Publish(ctx, key, raw).Err()
+}
+
+/* }}} */
+
+// updateOperation : Modify stored operation in transaction, retried if changed concurrently, finished ones are left untouched
+/* {{{ [updateOperation] */
+func updateOperation(ctx context.Context, id string, fn func(op *Operation)) error {
+	r, err := operationRedis()
+	if err != nil {
+		return err
+	}
+
+	key := operationKey(id)
+	for i := 0; i < OperationUpdateRetries; i++ {
+		err = r.Watch(ctx, func(tx *redis.Tx) error {
+			op, err := loadOperation(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+
+			if op.Finished() {
+				return nil
+			}
+
+			fn(op)
+			raw, err := op.encode()
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, raw, operationTTL())
+				pipe.Publish(ctx, key, raw)
+
+				return nil
+			})
+
+			return err
+		}, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+
+	return fmt.Errorf("Operation <%s> update conflicted %d times", id, OperationUpdateRetries)
+}
+
+/* }}} */
+
+/* {{{ [UniformMessage::Operation] */
+
+// Operate : Create operation and queue message as task, handler reports progress and result into operation
+func (msg *UniformMessage) Operate(target, method string) (*Operation, error) {
+	return msg.operate(target, method, "")
+}
+
+// operate : Create operation owned by caller (readable by anyone with ID if empty)
+func (msg *UniformMessage) operate(target, method, owner string) (*Operation, error) {
+	op := &Operation{
+		ID:        msg.ID,
+		Status:    OperationStatusPending,
+		CreatedAt: time.Now(),
+		owner:     owner,
+	}
+	err := op.save(msg.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Operation = op.ID
+	// Task outlives request, deadline of caller not inherited
+	msg.Deadline = time.Time{}
+	msg.Remaining = 0
+	err = msg.Task(target, method)
+	if err != nil {
+		msg.ReportError(ErrUnavailable)
+
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// ReportProgress : Report progress (0 - 100) of operation, ignored if message is not an operation
+func (msg *UniformMessage) ReportProgress(progress float64, message string) error {
+	if msg.Operation == "" {
+		return nil
+	}
+
+	return updateOperation(context.Background(), msg.Operation, func(op *Operation) {
+		op.Status = OperationStatusRunning
+		op.Progress = progress
+		op.Message = message
+	})
+}
+
+// ReportResult : Finish operation with result
+func (msg *UniformMessage) ReportResult(result interface{}) error {
+	if msg.Operation == "" {
+		return nil
+	}
+
+	msg.operationDone = true
+
+	return updateOperation(context.Background(), msg.Operation, func(op *Operation) {
+		op.Status = OperationStatusSucceeded
+		op.Progress = 100
+		op.Result = result
+	})
+}
+
+// ReportError : Finish operation with error
+func (msg *UniformMessage) ReportError(err error) error {
+	if msg.Operation == "" {
+		return nil
+	}
+
+	msg.operationDone = true
+	e := AsError(err)
+
+	return updateOperation(context.Background(), msg.Operation, func(op *Operation) {
+		op.Status = OperationStatusFailed
+		op.Error = &OperationError{
+			Code:        e.Code,
+			HTTPStatus:  e.HTTPStatus,
+			Message:     e.Message,
+			ErrorPrompt: e.ErrorPrompt,
+		}
+	})
+}
+
+// operationFinish : Report handler return as result of operation, if handler not reported itself
+func (msg *UniformMessage) operationFinish(ret *ResultMessage, err error) {
+	if msg.operationDone {
+		return
+	}
+
+	if err == nil && ret != nil {
+		err = ret.Err()
+	}
+
+	if err != nil {
+		err = msg.ReportError(err)
+	} else {
+		var result interface{}
+		if ret != nil && ret.Length() > 0 {
+			ret.Unmarshal(&result)
+		}
+
+		err = msg.ReportResult(result)
+	}
+
+	if err != nil {
+		Logger().Errorf("Operation <%s> : Report failed : %s", msg.Operation, err.Error())
+	}
+}
+
+/* }}} */
+
+/* {{{ [HTTP] */
+
+// EnableOperations : Expose operation status (GET <path>/{id}) and SSE events (GET <path>/{id}/events) to creator of operation, with route permissions if given
+/* {{{ [HTTPServer::EnableOperations] */
+func (s *HTTPServer) EnableOperations(path string, permissions uint64) {
+	if path == "" {
+		path = DefaultOperationPath
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	s.SetRoutes(
+		&HTTPRoute{
+			Name:        OperationRouteName,
+			Description: "Operation status",
+			Method:      "GET",
+			Path:        path + "/{id}",
+			Permissions: permissions,
+			Handler:     operationHandler,
+		},
+		&HTTPRoute{
+			Name:        OperationEventsRouteName,
+			Description: "Operation events (SSE)",
+			Method:      "GET",
+			Path:        path + "/{id}/events",
+			Permissions: permissions,
+			Handler:     operationEventsHandler,
+		},
+	)
+}
+
+/* }}} */
+
+// operationAccepted : Respond 202 with operation and status URL
+func operationAccepted(ctx *fasthttp.RequestCtx, e *HTTPResponseEnvelope, op *Operation) {
+	e.HTTPStatus = http.StatusAccepted
+	e.Message = "Accepted"
+	e.Data = op
+	if statusURL, err := HTTPURL(OperationRouteName, "id", op.ID); err == nil {
+		ctx.Response.Header.Set("Location", statusURL)
+		e.AddLink(LinkRelSelf, statusURL, fasthttp.MethodGet)
+		if eventsURL, err := HTTPURL(OperationEventsRouteName, "id", op.ID); err == nil {
+			e.AddLink("events", eventsURL, fasthttp.MethodGet)
+		}
+	}
+}
+
+// operationOf : Operation of request path, not found if caller is not the creator
+func operationOf(ctx *fasthttp.RequestCtx) (*Operation, error) {
+	id, _ := ctx.UserValue("id").(string)
+	op, err := GetOperation(HTTPContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.owner != "" && op.owner != operationOwner(ctx) {
+		// Existence not disclosed to others
+		return nil, Errorf(ErrorCodeNotFound, http.StatusNotFound, "Operation <%s> not found", id)
+	}
+
+	return op, nil
+}
+
+func operationHandler(ctx *fasthttp.RequestCtx) {
+	op, err := operationOf(ctx)
+	if err != nil {
+		HTTPEnvelopeError(ctx, err)
+
+		return
+	}
+
+	e := AcquireHTTPEnvelope()
+	e.Data = op
+	HTTPEnvelope(ctx, e)
+
+	return
+}
+
+// operationEventsHandler : Push operation state as server-sent events until finished
+/* {{{ [operationEventsHandler] */
+func operationEventsHandler(ctx *fasthttp.RequestCtx) {
+	op, err := operationOf(ctx)
+	if err != nil {
+		HTTPEnvelopeError(ctx, err)
+
+		return
+	}
+
+	id := op.ID
+	r, _ := operationRedis()
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		c := context.Background()
+		sub := r.Subscribe(c, operationKey(id))
+		defer sub.Close()
+
+		if _, err := sub.Receive(c); err != nil {
+			return
+		}
+
+		// Current state after subscribed, no update lost
+		op, err := GetOperation(c, id)
+		if err != nil || writeOperationEvent(w, op) != nil || op.Finished() {
+			return
+		}
+
+		ch := sub.Channel()
+		ticker := time.NewTicker(OperationKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				op := new(Operation)
+				if json.Unmarshal([]byte(m.Payload), op) != nil {
+					continue
+				}
+
+				if writeOperationEvent(w, op) != nil || op.Finished() {
+					return
+				}
+			case <-ticker.C:
+				// Disconnected client detected by failed write
+				w.WriteString(": keepalive\n\n")
+				if w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return
+}
+
+/* }}} */
+
+func writeOperationEvent(w *bufio.Writer, op *Operation) error {
+	raw, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", op.Status, raw)
+
+	return w.Flush()
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
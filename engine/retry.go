@@ -0,0 +1,254 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file retry.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Retry settings
+const (
+	DefaultRPCRetries       = 2
+	DefaultRPCBackoffBase   = 50 * time.Millisecond
+	DefaultRPCBackoffMax    = time.Second
+	DefaultRetryBudgetMax   = 10
+	DefaultRetryBudgetRatio = 0.1
+)
+
+// CallOptions : Options of RPC call. Zero fields fall back to options of target, then config "rpc.client.*"
+type CallOptions struct {
+	// Deadline of whole call including retries
+	Timeout time.Duration
+	// Retries after first attempt, negative disables retry
+	Retries     int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Safe to retry, methods of target may also be marked by SetIdempotentMethods
+	Idempotent bool
+}
+
+// retryBudget : Token bucket throttling retries of target, failures drain and successes refill
+type retryBudget struct {
+	lock   sync.Mutex
+	tokens float64
+}
+
+var (
+	targetCallOptions = make(map[string]*CallOptions)
+	idempotentMethods = make(map[string]map[string]bool)
+	retryBudgets      = make(map[string]*retryBudget)
+	callOptionsLock   sync.RWMutex
+	retryBudgetsLock  sync.Mutex
+)
+
+// SetCallOptions : Default options of calls to target
+/* {{{ [SetCallOptions] */
+func SetCallOptions(target string, opts *CallOptions) {
+	callOptionsLock.Lock()
+	targetCallOptions[_msgTarget(target)] = opts
+	callOptionsLock.Unlock()
+
+	return
+}
+
+/* }}} */
+
+// SetIdempotentMethods : Mark methods of target as safe to retry
+/* {{{ [SetIdempotentMethods] */
+func SetIdempotentMethods(target string, methods ...string) {
+	target = _msgTarget(target)
+	callOptionsLock.Lock()
+	set, ok := idempotentMethods[target]
+	if !ok {
+		set = make(map[string]bool)
+		idempotentMethods[target] = set
+	}
+
+	for _, method := range methods {
+		set[method] = true
+	}
+
+	callOptionsLock.Unlock()
+
+	return
+}
+
+/* }}} */
+
+// WithCallOptions : Options of this call, overriding options of target
+func (msg *UniformMessage) WithCallOptions(opts *CallOptions) *UniformMessage {
+	msg.options = opts
+
+	return msg
+}
+
+// callOptions : Effective options of call to method of target
+/* {{{ [callOptions] */
+func callOptions(target, method string, opts *CallOptions) *CallOptions {
+	ret := &CallOptions{
+		Timeout:     Config().GetDuration("rpc.client.timeout"),
+		Retries:     DefaultRPCRetries,
+		BackoffBase: rpcClientDuration("rpc.client.backoff_base", DefaultRPCBackoffBase),
+		BackoffMax:  rpcClientDuration("rpc.client.backoff_max", DefaultRPCBackoffMax),
+	}
+
+	if Config().IsSet("rpc.client.retries") {
+		ret.Retries = Config().GetInt("rpc.client.retries")
+	}
+
+	callOptionsLock.RLock()
+	ret.merge(targetCallOptions[target])
+	ret.Idempotent = ret.Idempotent || idempotentMethods[target][method]
+	callOptionsLock.RUnlock()
+	ret.merge(opts)
+
+	return ret
+}
+
+/* }}} */
+
+func (o *CallOptions) merge(opts *CallOptions) {
+	if opts == nil {
+		return
+	}
+
+	if opts.Timeout > 0 {
+		o.Timeout = opts.Timeout
+	}
+
+	if opts.Retries != 0 {
+		o.Retries = opts.Retries
+	}
+
+	if opts.BackoffBase > 0 {
+		o.BackoffBase = opts.BackoffBase
+	}
+
+	if opts.BackoffMax > 0 {
+		o.BackoffMax = opts.BackoffMax
+	}
+
+	o.Idempotent = o.Idempotent || opts.Idempotent
+
+	return
+}
+
+// backoff : Wait before retry, exponential with full jitter, false if context done meanwhile
+func (o *CallOptions) backoff(ctx context.Context, attempt int) bool {
+	d := o.BackoffBase << uint(attempt)
+	if d <= 0 || d > o.BackoffMax {
+		d = o.BackoffMax
+	}
+
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(d) + 1)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+/* {{{ [RetryBudget] */
+
+func getRetryBudget(target string) *retryBudget {
+	retryBudgetsLock.Lock()
+	defer retryBudgetsLock.Unlock()
+
+	b, ok := retryBudgets[target]
+	if !ok {
+		b = &retryBudget{tokens: retryBudgetMax()}
+		retryBudgets[target] = b
+	}
+
+	return b
+}
+
+func retryBudgetMax() float64 {
+	max := Config().GetFloat64("rpc.retry.budget_tokens")
+	if max <= 0 {
+		max = DefaultRetryBudgetMax
+	}
+
+	return max
+}
+
+// report : Failure costs one token, success refills by ratio
+func (b *retryBudget) report(failed bool) {
+	max := retryBudgetMax()
+	b.lock.Lock()
+	if failed {
+		b.tokens--
+		if b.tokens < 0 {
+			b.tokens = 0
+		}
+	} else {
+		ratio := Config().GetFloat64("rpc.retry.budget_ratio")
+		if ratio <= 0 {
+			ratio = DefaultRetryBudgetRatio
+		}
+
+		b.tokens += ratio
+		if b.tokens > max {
+			b.tokens = max
+		}
+	}
+
+	b.lock.Unlock()
+
+	return
+}
+
+// allow : Retry allowed while more than half of tokens left
+func (b *retryBudget) allow() bool {
+	max := retryBudgetMax()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.tokens > max/2
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
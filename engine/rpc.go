@@ -33,11 +33,14 @@ package engine
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -46,7 +49,7 @@ import (
 )
 
 const (
-	// RPCTCPPort : TCP port of RPC
+	// RPCTCPPort : Default TCP port of RPC
 	RPCTCPPort = 19080
 )
 
@@ -57,12 +60,11 @@ const (
 
 // RPCServer : HTTP2 (H2C) server
 type RPCServer struct {
-	addr        string
-	tls         bool
-	sslCertFile string
-	sslKeyFile  string
-	server      *http.Server
-	mux         *http.ServeMux
+	addr     string
+	tlsConf  *TLSConfig
+	server   *http.Server
+	mux      *http.ServeMux
+	listener net.Listener
 }
 
 func defaultRPCMux() *http.ServeMux {
@@ -87,75 +89,128 @@ func defaultRPCMux() *http.ServeMux {
 			return
 		}
 
-		self := _msgTarget(App().Name)
-		if msg.Reciever != self {
-			// Not you?
-			Logger().Errorf("RPC : Wrong message reciever : Self <%s> / Reciever <%s>", self, msg.Reciever)
-			w.WriteHeader(http.StatusNotAcceptable)
+		ret, cancel := dispatchRPC(r.Context(), msg, func(h *UniformMsgHandler) {
+			if h.streaming && r.Header.Get("Accept") == RPCStreamContentType {
+				msg.stream = newResultStream(w, msg.ctx)
+			}
+		})
+		// End frame of stream written within deadline context
+		defer cancel()
+
+		if msg.stream != nil {
+			err = msg.stream.finish(ret)
+			if err != nil {
+				Logger().Warnf("RPC stream <%s> to [%s] broken after %d chunks : %s", msg.Method, msg.Sender, msg.stream.Sent(), err.Error())
+			}
 
 			return
 		}
 
-		h := GetHandler(msg.Method)
-		if h == nil {
-			Logger().Errorf("RPC method handler <%s> not found", msg.Method)
-			w.WriteHeader(http.StatusNotFound)
+		writeRPCResult(w, ret)
 
-			return
-		}
+		return
+	})
 
-		if Config().GetBool("rpc.server.access_log") {
-			Logger().Debugf("RPC Access : <%s> from [%s]", msg.Method, msg.Sender)
-		}
+	return mux
+}
 
-		// Ignore concurrency
-		now0 := time.Now().UnixNano()
-		ret, err := h.hdr(msg)
-		now1 := time.Now().UnixNano()
-		if Config().GetBool("rpc.server.access_log") {
-			Logger().Debugf("RPC Access : <%s> from [%s], Elapsed time (nano seconds) : %d", msg.Method, msg.Sender, now1-now0)
-		}
+// dispatchRPC : Run handler of RPC message within deadline of caller, result carries error.
+// Prepare (if given) called with handler before run. Cancel releases deadline context of message, call it after result written
+func dispatchRPC(parent context.Context, msg *UniformMessage, prepare func(*UniformMsgHandler)) (*ResultMessage, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	self := _msgTarget(App().Name)
+	if msg.Reciever != self {
+		// Not you?
+		Logger().Errorf("RPC : Wrong message reciever : Self <%s> / Reciever <%s>", self, msg.Reciever)
 
-		if ret == nil {
-			ret = NewResultMessage(nil, false)
-		}
+		return NewErrorResultMessage(Errorf(ErrorCodeBadRequest, http.StatusNotAcceptable, "Wrong message reciever <%s>", msg.Reciever)), cancel
+	}
 
-		if err != nil {
-			Logger().Error(err)
-			if ret.Message == "OK" {
-				ret.Message = err.Error()
-			}
-		}
+	deadline := msg.Deadline
+	if msg.Remaining > 0 {
+		// Relative to local clock
+		deadline = time.Now().Add(msg.Remaining)
+	}
 
-		if ret.HTTPStatus == 0 {
-			ret.HTTPStatus = http.StatusOK
+	if !deadline.IsZero() {
+		if time.Now().After(deadline) {
+			// Caller gave up already
+			return NewErrorResultMessage(ErrTimeout), cancel
 		}
 
-		rba, err := ret.Encode()
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+		msg.ctx, cancel = context.WithDeadline(parent, deadline)
+	} else {
+		msg.ctx = parent
+	}
 
-			return
+	h := GetHandler(msg.Method)
+	if h == nil {
+		Logger().Errorf("RPC method handler <%s> not found", msg.Method)
+
+		return NewErrorResultMessage(Errorf(ErrorCodeNotFound, http.StatusNotFound, "RPC method handler <%s> not found", msg.Method)), cancel
+	}
+
+	err := acceptMessage(h, msg, TransportRPC)
+	if err != nil {
+		Logger().Errorf("RPC : Message <%s> rejected : %s", msg.ID, err.Error())
+
+		return NewErrorResultMessage(err), cancel
+	}
+
+	if Config().GetBool("rpc.server.access_log") {
+		Logger().Debugf("RPC Access : <%s> from [%s]", msg.Method, msg.Sender)
+	}
+
+	if prepare != nil {
+		prepare(h)
+	}
+
+	// Ignore concurrency
+	now0 := time.Now().UnixNano()
+	ret, err := h.call(msg)
+	now1 := time.Now().UnixNano()
+	if Config().GetBool("rpc.server.access_log") {
+		Logger().Debugf("RPC Access : <%s> from [%s], Elapsed time (nano seconds) : %d", msg.Method, msg.Sender, now1-now0)
+	}
+
+	if ret == nil {
+		ret = NewResultMessage(nil, false)
+	}
+
+	if err != nil {
+		Logger().Error(err)
+		if ret.Code == RPCCodeOK {
+			ret.SetError(err)
 		}
+	}
+
+	return ret, cancel
+}
 
-		w.WriteHeader(ret.HTTPStatus)
-		w.Write(rba)
+// writeRPCResult : Encode result message into response
+func writeRPCResult(w http.ResponseWriter, ret *ResultMessage) {
+	if ret.HTTPStatus == 0 {
+		ret.HTTPStatus = http.StatusOK
+	}
+
+	rba, err := ret.Encode()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 
 		return
-	})
+	}
 
-	return mux
+	w.WriteHeader(ret.HTTPStatus)
+	w.Write(rba)
+
+	return
 }
 
-// NewRPCServer : Create HTTP2 (h2c) instance by given parameters
+// NewRPCServer : Create HTTP2 (h2c) instance by given parameters, listening at config "rpc.server.addr" (":19080" by default)
 /* {{{ [NewRPCServer] */
 func NewRPCServer() *RPCServer {
-	addr := fmt.Sprintf(":%d", RPCTCPPort)
 	server := &RPCServer{
-		addr: addr,
-		server: &http.Server{
-			Addr: addr,
-		},
+		server: &http.Server{},
 	}
 
 	return server
@@ -163,6 +218,66 @@ func NewRPCServer() *RPCServer {
 
 /* }}} */
 
+// SetAddr : Set listening address, port 0 for any free port
+/* {{{ [RPCServer::SetAddr] */
+func (s *RPCServer) SetAddr(addr string) {
+	s.addr = addr
+
+	return
+}
+
+/* }}} */
+
+// Addr : Actual bound address after startup, configured one before
+/* {{{ [RPCServer::Addr] */
+func (s *RPCServer) Addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+
+	if s.addr == "" {
+		return rpcServerAddr()
+	}
+
+	return s.addr
+}
+
+/* }}} */
+
+func rpcServerAddr() string {
+	addr := Config().GetString("rpc.server.addr")
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", RPCTCPPort)
+	}
+
+	return addr
+}
+
+// port : Listening port, published to discovery
+func (s *RPCServer) port() int {
+	_, port, err := net.SplitHostPort(s.Addr())
+	if err != nil {
+		return RPCTCPPort
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return RPCTCPPort
+	}
+
+	return n
+}
+
+// SetTLS : Set TLS (mutual TLS if CA bundle given) configuration for RPC server
+/* {{{ [RPCServer::SetTLS] */
+func (s *RPCServer) SetTLS(conf *TLSConfig) {
+	s.tlsConf = conf
+
+	return
+}
+
+/* }}} */
+
 // Startup : Start and serve
 /* {{{ [RPCServer::Startup] */
 func (s *RPCServer) Startup(logger *logrus.Entry) {
@@ -170,17 +285,54 @@ func (s *RPCServer) Startup(logger *logrus.Entry) {
 		s.mux = defaultRPCMux()
 	}
 
+	h2s := &http2.Server{
+		IdleTimeout: Config().GetDuration("rpc.server.idle_timeout"),
+	}
+	if maxStreams := Config().GetInt("rpc.server.max_streams"); maxStreams > 0 {
+		h2s.MaxConcurrentStreams = uint32(maxStreams)
+	}
+
+	if s.addr == "" {
+		s.addr = rpcServerAddr()
+	}
+
+	if s.tlsConf == nil {
+		s.tlsConf = TLSConfigFromConfig("rpc.server.tls")
+	}
+
+	var tlsConf *tls.Config
+	if s.tlsConf != nil {
+		loader, err := NewTLSLoader(s.tlsConf)
+		if err != nil {
+			logger.Errorf("RPC server TLS failed : %s", err.Error())
+
+			return
+		}
+
+		tlsConf = loader.ServerConfig()
+	}
+
+	// Listen before return, bound address (port 0) known to discovery
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		logger.Errorf("RPC server listen at [%s] failed : %s", s.addr, err.Error())
+
+		return
+	}
+
+	s.listener = ln
 	go func() {
 		var failed error
-		if s.tls {
+		if tlsConf != nil {
 			s.server.Handler = s.mux
-			http2.ConfigureServer(s.server, &http2.Server{})
-			logger.Printf("RPC server initialized at [%s] with SSL", s.addr)
-			failed = s.server.ListenAndServeTLS(s.sslCertFile, s.sslKeyFile)
+			s.server.TLSConfig = tlsConf
+			http2.ConfigureServer(s.server, h2s)
+			logger.Printf("RPC server initialized at [%s] with SSL", s.Addr())
+			failed = s.server.ServeTLS(ln, "", "")
 		} else {
-			s.server.Handler = h2c.NewHandler(s.mux, &http2.Server{})
-			logger.Printf("RPC server initialized at [%s]", s.addr)
-			failed = s.server.ListenAndServe()
+			s.server.Handler = h2c.NewHandler(s.mux, h2s)
+			logger.Printf("RPC server initialized at [%s]", s.Addr())
+			failed = s.server.Serve(ln)
 		}
 
 		if failed != nil {
@@ -188,8 +340,6 @@ func (s *RPCServer) Startup(logger *logrus.Entry) {
 		}
 	}()
 
-	time.Sleep(100 * time.Microsecond)
-
 	return
 }
 
@@ -207,56 +357,217 @@ func (s *RPCServer) Shutdown() {
 
 // RPCClient : HTTP2 (h2c) client
 type RPCClient struct {
-	addr   string
-	client http.Client
+	addr      string
+	scheme    string
+	client    http.Client
+	transport *http2.Transport
+	streams   chan struct{}
+	// Unix nano time of last call and calls in flight, for idle eviction of pool
+	lastUsed    int64
+	outstanding int64
 }
 
-// NewRPCClient : Create RPC (HTTP2) client
-func NewRPCClient(addr string) *RPCClient {
+var (
+	rpcClientTLS *TLSLoader
+)
+
+// SetRPCClientTLS : Enable TLS (client certificate if given) for all RPC calls
+/* {{{ [SetRPCClientTLS] */
+func SetRPCClientTLS(conf *TLSConfig) error {
+	if conf == nil {
+		if rpcClientTLS != nil {
+			rpcClientTLS.Close()
+		}
+
+		rpcClientTLS = nil
+		CloseRPCClients()
+
+		return nil
+	}
+
+	loader, err := NewTLSLoader(conf)
+	if err != nil {
+		return err
+	}
+
+	if rpcClientTLS != nil {
+		rpcClientTLS.Close()
+	}
+
+	rpcClientTLS = loader
+	// Pooled clients dialed without TLS
+	CloseRPCClients()
+
+	return nil
+}
+
+/* }}} */
+
+// rpcClientAddr : Address with default port appended if no port given
+func rpcClientAddr(addr string) string {
 	if addr == "" {
 		addr = "localhost"
 	}
 
-	addr = fmt.Sprintf("%s:%d", addr, RPCTCPPort)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = fmt.Sprintf("%s:%d", addr, RPCTCPPort)
+	}
+
+	return addr
+}
+
+// RPCTarget : Address of RPC target listed in config "rpc.client.targets", used if no service registry set
+type RPCTarget struct {
+	Name string `mapstructure:"name"`
+	Addr string `mapstructure:"addr"`
+	Port int    `mapstructure:"port"`
+	// RPCTransportH2C or RPCTransportNats
+	Transport string `mapstructure:"transport"`
+}
+
+// rpcTargets : Targets of config "rpc.client.targets", parsed again on config reload
+var rpcTargets = newConfigCache(func() interface{} {
+	targets := []*RPCTarget{}
+	err := Config().UnmarshalKey("rpc.client.targets", &targets)
+	if err != nil {
+		Logger().Warnf("RPC : Invalid config of client targets : %s", err.Error())
+
+		return []*RPCTarget{}
+	}
+
+	return targets
+})
+
+// rpcTargetAddr : Address of target from config, target itself as hostname on default port if not listed
+func rpcTargetAddr(target string) string {
+	for _, t := range rpcTargets.get().([]*RPCTarget) {
+		if t == nil || _msgTarget(t.Name) != target {
+			continue
+		}
+
+		addr := t.Addr
+		if addr == "" {
+			addr = target
+		}
+
+		port := t.Port
+		if port == 0 {
+			port = RPCTCPPort
+		}
+
+		return net.JoinHostPort(addr, strconv.Itoa(port))
+	}
+
+	return target
+}
+
+// newRPCClient : Client on transport with health check of idle connections and streams limit
+func newRPCClient(addr, scheme string, transport *http2.Transport) *RPCClient {
+	transport.ReadIdleTimeout = rpcClientDuration("rpc.client.health_check", DefaultRPCHealthCheck)
+	transport.PingTimeout = rpcClientDuration("rpc.client.ping_timeout", DefaultRPCPingTimeout)
 	client := &RPCClient{
-		addr: addr,
-		client: http.Client{
-			// Ignore SSL (h2c)
-			Transport: &http2.Transport{
-				AllowHTTP: true,
-				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-					return net.Dial(network, addr)
-				},
-			},
-		},
+		addr:      addr,
+		scheme:    scheme,
+		client:    http.Client{Transport: transport},
+		transport: transport,
+		lastUsed:  time.Now().UnixNano(),
+	}
+
+	if maxStreams := Config().GetInt("rpc.client.max_streams"); maxStreams > 0 {
+		client.streams = make(chan struct{}, maxStreams)
 	}
 
 	return client
 }
 
+// NewRPCClient : Create RPC (HTTP2) client, default port appended if address has no port
+func NewRPCClient(addr string) *RPCClient {
+	addr = rpcClientAddr(addr)
+	if rpcClientTLS != nil {
+		return NewRPCClientTLS(addr, rpcClientTLS)
+	}
+
+	dialTimeout := rpcClientDuration("rpc.client.dial_timeout", DefaultRPCDialTimeout)
+
+	return newRPCClient(addr, "http", &http2.Transport{
+		// Ignore SSL (h2c)
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.DialTimeout(network, addr, dialTimeout)
+		},
+	})
+}
+
+// NewRPCClientTLS : Create RPC (HTTP2 over TLS) client to given address (with port)
+func NewRPCClientTLS(addr string, loader *TLSLoader) *RPCClient {
+	dialTimeout := rpcClientDuration("rpc.client.dial_timeout", DefaultRPCDialTimeout)
+
+	return newRPCClient(addr, "https", &http2.Transport{
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			// Config generated per connection, reloaded CA bundle reaches pooled clients
+			cfg := loader.ClientConfig()
+			cfg.NextProtos = []string{http2.NextProtoTLS}
+
+			return tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, network, addr, cfg)
+		},
+	})
+}
+
+// Close : Close idle connections of client
+func (c *RPCClient) Close() {
+	c.transport.CloseIdleConnections()
+
+	return
+}
+
 // Call : Call RPC
 /* {{{ [RPCClient::Call] */
 func (c *RPCClient) Call(payload []byte) (*ResultMessage, error) {
-	resp, err := c.client.Post("http://"+c.addr, "application/msgpack", bytes.NewBuffer(payload))
+	return c.CallContext(context.Background(), payload)
+}
+
+/* }}} */
+
+// CallContext : Call RPC, abandoned when context done
+/* {{{ [RPCClient::CallContext] */
+func (c *RPCClient) CallContext(ctx context.Context, payload []byte) (*ResultMessage, error) {
+	release, err := c.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	defer func() {
-		resp.Body.Close()
-		c.client.CloseIdleConnections()
-	}()
+	defer release()
 
-	if resp.StatusCode < 400 {
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.scheme+"://"+c.addr, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/msgpack")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, rpcContextError(ctx, err)
+	}
+
+	// Connection kept for following calls
+	defer resp.Body.Close()
 
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > 0 {
+		// Result message carries error (if any) itself
 		r := NewResultMessage(nil, false)
-		r.Decode(body)
+		err = r.Decode(body)
+		if err == nil {
+			return r, r.Err()
+		}
 
-		return r, nil
+		if resp.StatusCode < 400 {
+			return nil, err
+		}
 	}
 
 	//Logger().Debugf("RPC call response with status %d", resp.StatusCode)
@@ -266,6 +577,36 @@ func (c *RPCClient) Call(payload []byte) (*ResultMessage, error) {
 
 /* }}} */
 
+// acquire : Take stream slot (if limited) and count call in flight, released by returned function
+func (c *RPCClient) acquire(ctx context.Context) (func(), error) {
+	if c.streams != nil {
+		select {
+		case c.streams <- struct{}{}:
+		case <-ctx.Done():
+			return nil, rpcContextError(ctx, ctx.Err())
+		}
+	}
+
+	atomic.AddInt64(&c.outstanding, 1)
+
+	return func() {
+		atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+		atomic.AddInt64(&c.outstanding, -1)
+		if c.streams != nil {
+			<-c.streams
+		}
+	}, nil
+}
+
+// rpcContextError : Timeout error if deadline of call exceeded
+func rpcContextError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return NewError(ErrTimeout.Code, ErrTimeout.HTTPStatus, ErrTimeout.Message).Wrap(err)
+	}
+
+	return err
+}
+
 /*
  * Local variables:
  * tab-width: 4
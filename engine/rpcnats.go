@@ -0,0 +1,193 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file rpcnats.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// RPC transports, chosen per target by "transport" of config "rpc.client.targets" ("rpc.client.transport" by default)
+const (
+	RPCTransportH2C  = "h2c"
+	RPCTransportNats = "nats"
+)
+
+// NATS RPC settings, nodes answer in queue group if config "rpc.nats.enabled" set
+const (
+	RPCTopicPrefix            = "_.rpc_"
+	DefaultRPCNatsTimeout     = 10 * time.Second
+	DefaultRPCNatsConcurrency = 256
+)
+
+var (
+	// Requests handled at the same time, config "rpc.nats.concurrency"
+	rpcNatsSlots     chan struct{}
+	rpcNatsSlotsOnce sync.Once
+)
+
+// rpcTargetTransport : Transport of calls to target
+func rpcTargetTransport(target string) string {
+	for _, t := range rpcTargets.get().([]*RPCTarget) {
+		if t != nil && _msgTarget(t.Name) == target && t.Transport != "" {
+			return t.Transport
+		}
+	}
+
+	transport := Config().GetString("rpc.client.transport")
+	if transport == "" {
+		transport = RPCTransportH2C
+	}
+
+	return transport
+}
+
+// _rpcNatsConsumerHandler : Requests of queue group, each handled in its own goroutine with bounded concurrency.
+// Subscription blocked when all slots taken, deadline of caller ("rpc.nats.timeout" if none) applied
+func _rpcNatsConsumerHandler(m *nats.Msg) {
+	rpcNatsSlotsOnce.Do(func() {
+		n := Config().GetInt("rpc.nats.concurrency")
+		if n <= 0 {
+			n = DefaultRPCNatsConcurrency
+		}
+
+		rpcNatsSlots = make(chan struct{}, n)
+	})
+
+	rpcNatsSlots <- struct{}{}
+	go func() {
+		defer func() {
+			<-rpcNatsSlots
+		}()
+
+		msg := NewUniformMessage(nil, false)
+		err := msg.Decode(m.Data)
+		if err != nil {
+			// Msgpack failed
+			Logger().Error(err)
+			respondRPCNats(m, NewErrorResultMessage(NewError(ErrBadRequest.Code, ErrBadRequest.HTTPStatus, ErrBadRequest.Message).Wrap(err)))
+
+			return
+		}
+
+		ctx := context.Background()
+		if msg.Remaining <= 0 && msg.Deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, rpcClientDuration("rpc.nats.timeout", DefaultRPCNatsTimeout))
+			defer cancel()
+		}
+
+		ret, cancel := dispatchRPC(ctx, msg, nil)
+		respondRPCNats(m, ret)
+		cancel()
+	}()
+
+	return
+}
+
+// respondRPCNats : Encode result message into reply
+func respondRPCNats(m *nats.Msg, ret *ResultMessage) {
+	if m.Reply == "" {
+		// Nobody waiting
+		return
+	}
+
+	if ret.HTTPStatus == 0 {
+		ret.HTTPStatus = http.StatusOK
+	}
+
+	rba, err := ret.Encode()
+	if err != nil {
+		Logger().Error(err)
+
+		return
+	}
+
+	err = m.Respond(rba)
+	if err != nil {
+		Logger().Errorf("RPC : NATS reply failed : %s", err.Error())
+	}
+
+	return
+}
+
+// natsRPCRequest : Request reciever over NATS, answered by one instance of queue group
+func natsRPCRequest(ctx context.Context, reciever string, payload []byte) (*ResultMessage, error) {
+	nc := Nats()
+	if nc == nil {
+		return nil, NewError(ErrUnavailable.Code, ErrUnavailable.HTTPStatus, "No NATS connection")
+	}
+
+	m, err := nc.RequestWithContext(ctx, RPCTopicPrefix+reciever, payload)
+	if err != nil {
+		return nil, natsRPCError(ctx, err)
+	}
+
+	if len(m.Data) == 0 {
+		// No responder status (without header support) from server
+		return nil, Errorf(ErrorCodeUnavailable, http.StatusServiceUnavailable, "No RPC responder of <%s>", reciever)
+	}
+
+	r := NewResultMessage(nil, false)
+	err = r.Decode(m.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, r.Err()
+}
+
+// natsRPCError : Errors of NATS request as the same of h2c calls
+func natsRPCError(ctx context.Context, err error) error {
+	switch err {
+	case nats.ErrTimeout, context.DeadlineExceeded:
+		// No responder ends in timeout too, without header support of client
+		return NewError(ErrTimeout.Code, ErrTimeout.HTTPStatus, ErrTimeout.Message).Wrap(err)
+	case nats.ErrNoServers, nats.ErrConnectionClosed, nats.ErrInvalidConnection, nats.ErrDisconnected, nats.ErrReconnectBufExceeded:
+		return NewError(ErrUnavailable.Code, ErrUnavailable.HTTPStatus, ErrUnavailable.Message).Wrap(err)
+	}
+
+	return rpcContextError(ctx, err)
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,145 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file rpcpool.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RPC client pool settings, overridden by config "rpc.client.idle_timeout", "health_check", "ping_timeout" and "dial_timeout"
+const (
+	DefaultRPCIdleTimeout = 90 * time.Second
+	DefaultRPCHealthCheck = 15 * time.Second
+	DefaultRPCPingTimeout = 5 * time.Second
+	DefaultRPCDialTimeout = 3 * time.Second
+)
+
+var (
+	rpcPool        = make(map[string]*RPCClient)
+	rpcPoolLock    sync.Mutex
+	rpcPoolJanitor sync.Once
+)
+
+func rpcClientDuration(key string, def time.Duration) time.Duration {
+	if Config() == nil {
+		return def
+	}
+
+	d := Config().GetDuration(key)
+	if d <= 0 {
+		d = def
+	}
+
+	return d
+}
+
+// GetRPCClient : Pooled client of address (port appended if absent), calls multiplexed over long-lived connections
+/* {{{ [GetRPCClient] */
+func GetRPCClient(addr string) *RPCClient {
+	addr = rpcClientAddr(addr)
+	rpcPoolLock.Lock()
+	defer rpcPoolLock.Unlock()
+
+	c, ok := rpcPool[addr]
+	if !ok {
+		c = NewRPCClient(addr)
+		rpcPool[addr] = c
+		rpcPoolJanitor.Do(func() {
+			go rpcPoolEvict()
+		})
+	}
+
+	return c
+}
+
+/* }}} */
+
+// rpcOutstanding : Calls in flight to address through pooled client
+func rpcOutstanding(addr string) int64 {
+	rpcPoolLock.Lock()
+	c, ok := rpcPool[addr]
+	rpcPoolLock.Unlock()
+	if !ok {
+		return 0
+	}
+
+	return atomic.LoadInt64(&c.outstanding)
+}
+
+// CloseRPCClients : Close and drop all pooled clients
+/* {{{ [CloseRPCClients] */
+func CloseRPCClients() {
+	rpcPoolLock.Lock()
+	for addr, c := range rpcPool {
+		c.Close()
+		delete(rpcPool, addr)
+	}
+
+	rpcPoolLock.Unlock()
+
+	return
+}
+
+/* }}} */
+
+// rpcPoolEvict : Close clients without calls in idle timeout, dialed again when needed
+/* {{{ [rpcPoolEvict] */
+func rpcPoolEvict() {
+	for {
+		idle := rpcClientDuration("rpc.client.idle_timeout", DefaultRPCIdleTimeout)
+		time.Sleep(idle / 2)
+
+		deadline := time.Now().Add(-idle).UnixNano()
+		rpcPoolLock.Lock()
+		for addr, c := range rpcPool {
+			if atomic.LoadInt64(&c.outstanding) == 0 && atomic.LoadInt64(&c.lastUsed) < deadline {
+				c.Close()
+				delete(rpcPool, addr)
+			}
+		}
+
+		rpcPoolLock.Unlock()
+	}
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
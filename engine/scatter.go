@@ -0,0 +1,243 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file scatter.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Scatter-gather settings
+const (
+	DefaultScatterParallelism = 8
+)
+
+// CallFuture : Pending result of asynchronous call
+type CallFuture struct {
+	done   chan struct{}
+	result *ResultMessage
+	err    error
+}
+
+// CallRequest : One call of scatter-gather
+type CallRequest struct {
+	Reciever string
+	Method   string
+	Data     interface{}
+	Compress bool
+	// Key of consistent hashing balancer
+	Key     string
+	Options *CallOptions
+}
+
+// CallResponse : Result of one call of scatter-gather, error of its own
+type CallResponse struct {
+	Request  *CallRequest
+	Instance *ServiceInstance
+	Result   *ResultMessage
+	Err      error
+}
+
+// ScatterOptions : Parallelism (config "rpc.scatter.parallelism" by default) and deadline of all calls
+type ScatterOptions struct {
+	Parallelism int
+	Timeout     time.Duration
+}
+
+/* {{{ [CallFuture] */
+
+// CallAsync : Call in background, message must not be touched until future done
+func (msg *UniformMessage) CallAsync(reciever, method string) *CallFuture {
+	f := &CallFuture{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.result, f.err = msg.Call(reciever, method)
+	}()
+
+	return f
+}
+
+// Done : Closed when call finished
+func (f *CallFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait : Block until call finished
+func (f *CallFuture) Wait() (*ResultMessage, error) {
+	<-f.done
+
+	return f.result, f.err
+}
+
+// WaitContext : Block until call finished or context done, call goes on with its own deadline
+func (f *CallFuture) WaitContext(ctx context.Context) (*ResultMessage, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, rpcContextError(ctx, ctx.Err())
+	}
+}
+
+/* }}} */
+
+// CallMany : Fan calls out with bounded parallelism, responses in order of requests
+/* {{{ [CallMany] */
+func CallMany(ctx context.Context, reqs []*CallRequest, opts *ScatterOptions) []*CallResponse {
+	resps := make([]*CallResponse, len(reqs))
+	for i, req := range reqs {
+		resps[i] = &CallResponse{Request: req}
+	}
+
+	scatter(ctx, opts, resps, func(ctx context.Context, resp *CallResponse) {
+		req := resp.Request
+		msg := NewUniformMessage(req.Data, req.Compress).WithContext(ctx).WithBalanceKey(req.Key).WithCallOptions(req.Options)
+		resp.Result, resp.Err = msg.Call(req.Reciever, req.Method)
+	})
+
+	return resps
+}
+
+/* }}} */
+
+// CallAll : Call method on every discovered instance of service, responses in order of instances
+/* {{{ [CallAll] */
+func CallAll(ctx context.Context, reciever, method string, data interface{}, opts *ScatterOptions) ([]*CallResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if Registry() == nil {
+		return nil, NewError(ErrorCodeUnavailable, http.StatusServiceUnavailable, "No service registry")
+	}
+
+	instances, err := ResolveService(ctx, _msgTarget(reciever))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &CallRequest{
+		Reciever: reciever,
+		Method:   method,
+		Data:     data,
+	}
+	resps := make([]*CallResponse, len(instances))
+	for i, inst := range instances {
+		resps[i] = &CallResponse{
+			Request:  req,
+			Instance: inst,
+		}
+	}
+
+	scatter(ctx, opts, resps, func(ctx context.Context, resp *CallResponse) {
+		msg := NewUniformMessage(data, false).WithContext(ctx)
+		msg.instance = resp.Instance
+		resp.Result, resp.Err = msg.Call(reciever, method)
+	})
+
+	return resps, nil
+}
+
+/* }}} */
+
+// scatter : Run call of each response with bounded parallelism under global deadline
+/* {{{ [scatter] */
+func scatter(ctx context.Context, opts *ScatterOptions, resps []*CallResponse, call func(context.Context, *CallResponse)) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	parallelism := 0
+	if opts != nil {
+		parallelism = opts.Parallelism
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+	}
+
+	if parallelism <= 0 {
+		parallelism = Config().GetInt("rpc.scatter.parallelism")
+	}
+
+	if parallelism <= 0 {
+		parallelism = DefaultScatterParallelism
+	}
+
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, resp := range resps {
+		if err := ctx.Err(); err != nil {
+			// Checked first, select picks randomly if slot free too
+			resp.Err = rpcContextError(ctx, err)
+
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// Never started
+			resp.Err = rpcContextError(ctx, ctx.Err())
+
+			continue
+		}
+
+		wg.Add(1)
+		go func(resp *CallResponse) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			call(ctx, resp)
+		}(resp)
+	}
+
+	wg.Wait()
+
+	return
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -45,7 +45,10 @@ func _rtSchedule(h *UniformMsgHandler, ch chan *UniformMessage) {
 					break
 				}
 
-				h.hdr(msg)
+				_, err := h.call(msg)
+				if err != nil {
+					Logger().Error(err)
+				}
 			}
 		}()
 	}
@@ -103,14 +106,14 @@ func runHandler(h *UniformMsgHandler, msg *UniformMessage) {
 		ch <- msg
 	} else if h.concurrency == 0 {
 		// Blocking
-		_, err := h.hdr(msg)
+		_, err := h.call(msg)
 		if err != nil {
 			Logger().Error(err)
 		}
 	} else {
 		// All passthru
 		go func() {
-			_, err := h.hdr(msg)
+			_, err := h.call(msg)
 			if err != nil {
 				Logger().Error(err)
 			}
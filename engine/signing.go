@@ -0,0 +1,295 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file signing.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Message signing settings
+const (
+	DefaultMessageMaxAge = 5 * time.Minute
+)
+
+// Message transports, for freshness window and interceptors
+const (
+	TransportRPC     = "rpc"
+	TransportTask    = "task"
+	TransportNotify  = "notify"
+	TransportJSONRPC = "jsonrpc"
+)
+
+// SigningKey : HMAC key of service listed in config "security.keys", ID referenced by messages for rotation
+type SigningKey struct {
+	Service string `mapstructure:"service"`
+	ID      string `mapstructure:"id"`
+	Secret  string `mapstructure:"secret"`
+}
+
+// replayCache : IDs of messages seen in freshness window
+type replayCache struct {
+	lock      sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+var (
+	messageReplays = &replayCache{seen: make(map[string]time.Time)}
+)
+
+// signingKeys : Keys of config "security.keys", parsed again on config reload
+var signingKeys = newConfigCache(func() interface{} {
+	keys := []*SigningKey{}
+	err := Config().UnmarshalKey("security.keys", &keys)
+	if err != nil {
+		Logger().Warnf("Signing : Invalid config of keys : %s", err.Error())
+
+		return []*SigningKey{}
+	}
+
+	return keys
+})
+
+// signingKey : Key of service by ID
+func signingKey(service, id string) *SigningKey {
+	keys := signingKeys.get().([]*SigningKey)
+	for _, k := range keys {
+		if k == nil || k.ID != id || k.Secret == "" {
+			continue
+		}
+
+		if k.Service == service || _msgTarget(k.Service) == service {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// signingBytes : Canonical form of signed fields, independent of encoding
+/* {{{ [UniformMessage::signingBytes] */
+func (msg *UniformMessage) signingBytes() []byte {
+	var buf bytes.Buffer
+	n := make([]byte, 8)
+	writeInt := func(v int64) {
+		binary.BigEndian.PutUint64(n, uint64(v))
+		buf.Write(n)
+	}
+
+	writeBytes := func(b []byte) {
+		writeInt(int64(len(b)))
+		buf.Write(b)
+	}
+
+	for _, f := range []string{msg.ID, msg.Sender, msg.Reciever, msg.Method, msg.Operation, msg.KeyID} {
+		writeBytes([]byte(f))
+	}
+
+	for _, t := range []time.Time{msg.Time, msg.Deadline} {
+		if t.IsZero() {
+			writeInt(0)
+		} else {
+			writeInt(t.UnixNano())
+		}
+	}
+
+	writeInt(int64(msg.Remaining))
+	writeInt(int64(msg.Attempt))
+	if msg.Compress {
+		writeInt(1)
+	} else {
+		writeInt(0)
+	}
+
+	writeBytes(msg.Data)
+
+	return buf.Bytes()
+}
+
+/* }}} */
+
+// sign : Sign message with current key (config "security.key_id") of sender, unsigned if no key set
+/* {{{ [UniformMessage::sign] */
+func (msg *UniformMessage) sign() error {
+	if Config() == nil {
+		return nil
+	}
+
+	id := Config().GetString("security.key_id")
+	if id == "" {
+		return nil
+	}
+
+	k := signingKey(msg.Sender, id)
+	if k == nil {
+		return fmt.Errorf("No signing key <%s> of service <%s>", id, msg.Sender)
+	}
+
+	msg.KeyID = k.ID
+	mac := hmac.New(sha256.New, []byte(k.Secret))
+	mac.Write(msg.signingBytes())
+	msg.Signature = mac.Sum(nil)
+
+	return nil
+}
+
+/* }}} */
+
+// verify : Check signature, freshness (config "security.max_age", "security.<transport>_max_age") and replay (except tasks) of incoming message if config "security.verify" set
+/* {{{ [UniformMessage::verify] */
+func (msg *UniformMessage) verify(transport string) error {
+	if !Config().GetBool("security.verify") {
+		return nil
+	}
+
+	unauthorized := func(format string, args ...interface{}) error {
+		return Errorf(ErrorCodeForbidden, http.StatusUnauthorized, format, args...)
+	}
+
+	if len(msg.Signature) == 0 {
+		return unauthorized("Unsigned message from <%s>", msg.Sender)
+	}
+
+	k := signingKey(msg.Sender, msg.KeyID)
+	if k == nil {
+		return unauthorized("Unknown signing key <%s> of <%s>", msg.KeyID, msg.Sender)
+	}
+
+	mac := hmac.New(sha256.New, []byte(k.Secret))
+	mac.Write(msg.signingBytes())
+	if !hmac.Equal(mac.Sum(nil), msg.Signature) {
+		return unauthorized("Invalid signature of message from <%s>", msg.Sender)
+	}
+
+	// Queued tasks may wait long, fresh checked only if "security.task_max_age" set
+	maxAge := Config().GetDuration("security." + transport + "_max_age")
+	fresh := maxAge > 0 || transport != TransportTask
+	if maxAge <= 0 {
+		maxAge = rpcClientDuration("security.max_age", DefaultMessageMaxAge)
+	}
+
+	age := time.Since(msg.Time)
+	if fresh && (age > maxAge || age < -maxAge) {
+		return unauthorized("Stale message from <%s>, age %s", msg.Sender, age)
+	}
+
+	if transport == TransportTask {
+		// Redelivered by NSQ with the same body on requeue or timeout, tasks are at-least-once
+		return nil
+	}
+
+	if !messageReplays.add(fmt.Sprintf("%s#%d", msg.ID, msg.Attempt), maxAge*2) {
+		return unauthorized("Replayed message <%s> from <%s>", msg.ID, msg.Sender)
+	}
+
+	return nil
+}
+
+/* }}} */
+
+// add : False if ID seen within window
+func (c *replayCache) add(id string, window time.Duration) bool {
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if now.Sub(c.lastPrune) > window {
+		for k, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, k)
+			}
+		}
+
+		c.lastPrune = now
+	}
+
+	if expires, ok := c.seen[id]; ok && now.Before(expires) {
+		return false
+	}
+
+	c.seen[id] = now.Add(window)
+
+	return true
+}
+
+// AllowSenders : Restrict handler to messages from given services
+/* {{{ [AllowSenders] */
+func AllowSenders(method string, senders ...string) {
+	h := GetHandler(method)
+	if h == nil {
+		return
+	}
+
+	h.senders = make(map[string]bool, len(senders))
+	for _, sender := range senders {
+		h.senders[sender] = true
+		h.senders[_msgTarget(sender)] = true
+	}
+
+	return
+}
+
+/* }}} */
+
+// acceptMessage : Verify message and check sender allowlist of handler.
+// Messages of JSON-RPC built locally and unsigned, authorized by permissions of route instead
+func acceptMessage(h *UniformMsgHandler, msg *UniformMessage, transport string) error {
+	msg.transport = transport
+	if transport != TransportJSONRPC {
+		err := msg.verify(transport)
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.senders != nil && !h.senders[msg.Sender] {
+		return Errorf(ErrorCodeForbidden, http.StatusForbidden, "Sender <%s> not allowed for <%s>", msg.Sender, h.method)
+	}
+
+	return nil
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,420 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file stream.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stream settings
+const (
+	// RPCStreamContentType : Accepted by streaming client, response of streaming handler
+	RPCStreamContentType  = "application/x-msgpack-stream"
+	DefaultRPCStreamFrame = 16 * 1024 * 1024
+)
+
+// Frame kinds, each frame is 1 byte kind + 4 bytes big-endian length + msgpack encoded ResultMessage
+const (
+	rpcFrameChunk = 0
+	rpcFrameEnd   = 1
+)
+
+// UniformStreamFunc : Streaming RPC handler, chunks sent by stream until returned
+type UniformStreamFunc func(*UniformMessage, *ResultStream) error
+
+// ResultStream : Server side of streaming call, writes blocked by HTTP2 flow control of client
+type ResultStream struct {
+	w       http.ResponseWriter
+	ctx     context.Context
+	lock    sync.Mutex
+	started bool
+	sent    int64
+}
+
+// ResultIterator : Client side of streaming call
+type ResultIterator struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	release func()
+	cur     *ResultMessage
+	err     error
+	done    bool
+	// Unary response of handler not streaming, yielded as the only chunk
+	unary *ResultMessage
+}
+
+/* {{{ [Server] */
+
+// RegisterStreamHandler : Add streaming handler to pool, called by UniformMessage.Stream only
+func RegisterStreamHandler(method string, handler UniformStreamFunc, cv ...int64) {
+	RegisterHandler(method, func(msg *UniformMessage) (*ResultMessage, error) {
+		if msg.stream == nil {
+			return nil, Errorf(ErrorCodeBadRequest, http.StatusNotAcceptable, "Handler <%s> is streaming, call by stream", method)
+		}
+
+		return nil, handler(msg, msg.stream)
+	}, cv...)
+
+	h := GetHandler(method)
+	if h != nil {
+		h.streaming = true
+	}
+
+	return
+}
+
+func newResultStream(w http.ResponseWriter, ctx context.Context) *ResultStream {
+	return &ResultStream{
+		w:   w,
+		ctx: ctx,
+	}
+}
+
+// Context : Cancelled when client gone (stream reset) or deadline exceeded
+func (s *ResultStream) Context() context.Context {
+	return s.ctx
+}
+
+// Sent : Number of chunks sent
+func (s *ResultStream) Sent() int64 {
+	return atomic.LoadInt64(&s.sent)
+}
+
+// Send : Send one chunk, blocked until client window open, failed if client gone
+func (s *ResultStream) Send(ret *ResultMessage) error {
+	if ret == nil {
+		return nil
+	}
+
+	err := s.write(rpcFrameChunk, ret)
+	if err == nil {
+		atomic.AddInt64(&s.sent, 1)
+	}
+
+	return err
+}
+
+// SendData : Send data as one chunk
+func (s *ResultStream) SendData(data interface{}, compress bool) error {
+	return s.Send(NewResultMessage(data, compress))
+}
+
+// finish : End frame with status (error) of handler
+func (s *ResultStream) finish(ret *ResultMessage) error {
+	if ret.HTTPStatus == 0 {
+		ret.HTTPStatus = http.StatusOK
+	}
+
+	return s.write(rpcFrameEnd, ret)
+}
+
+func (s *ResultStream) write(kind byte, ret *ResultMessage) error {
+	if err := s.ctx.Err(); err != nil {
+		// Client gone
+		return rpcContextError(s.ctx, err)
+	}
+
+	rba, err := ret.Encode()
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 5, 5+len(rba))
+	frame[0] = kind
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(rba)))
+	frame = append(frame, rba...)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.started {
+		s.w.Header().Set("Content-Type", RPCStreamContentType)
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	_, err = s.w.Write(frame)
+	if err != nil {
+		return rpcContextError(s.ctx, err)
+	}
+
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+/* }}} */
+
+/* {{{ [Client] */
+
+// Stream : Streaming RPC via HTTP2, chunks read by iterator which must be closed.
+// Lasts until context of message done, not retried
+func (msg *UniformMessage) Stream(reciever, method string) (*ResultIterator, error) {
+	msg.Reciever = _msgTarget(reciever)
+	msg.Method = method
+	msg.Sender = App().Name
+
+	if rpcTargetTransport(msg.Reciever) == RPCTransportNats {
+		return nil, Errorf(ErrorCodeBadRequest, http.StatusNotAcceptable, "Streaming call to <%s> not supported over NATS", reciever)
+	}
+
+	ctx := msg.Context()
+	inst, addr, err := resolveRPCTarget(ctx, msg.Reciever, msg.balanceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker := getBreaker(msg.Reciever, addr)
+	probe, ok := breaker.allow()
+	if !ok {
+		return nil, circuitOpenError(breakerKey(msg.Reciever, addr))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.Remaining = time.Until(deadline)
+	}
+
+	payload, err := msg.Encode()
+	if err != nil {
+		breaker.release(probe)
+
+		return nil, err
+	}
+
+	it, err := GetRPCClient(addr).StreamContext(ctx, payload)
+	failed := it == nil && err != nil
+	breaker.finish(ctx, probe, failed)
+	if inst != nil {
+		reportInstance(msg.Reciever, inst, failed)
+	}
+
+	if err != nil {
+		Logger().Errorf("RPC stream to <%s>:[%s] failed : %s", reciever, method, err.Error())
+
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// StreamContext : Open streaming call, stream slot of client held until iterator closed
+/* {{{ [RPCClient::StreamContext] */
+func (c *RPCClient) StreamContext(ctx context.Context, payload []byte) (*ResultIterator, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.scheme+"://"+c.addr, bytes.NewBuffer(payload))
+	if err != nil {
+		release()
+
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", RPCStreamContentType)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		release()
+
+		return nil, rpcContextError(ctx, err)
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), RPCStreamContentType) {
+		return &ResultIterator{
+			ctx:     ctx,
+			body:    resp.Body,
+			release: release,
+		}, nil
+	}
+
+	// Handler not streaming, or rejected before stream started
+	defer release()
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewResultMessage(nil, false)
+	if len(body) == 0 || r.Decode(body) != nil {
+		return nil, fmt.Errorf("Invalid RPC stream, response with HTTP status %d", resp.StatusCode)
+	}
+
+	// Error (if any) reported by Next as end of stream
+	return &ResultIterator{
+		ctx:     ctx,
+		release: func() {},
+		unary:   r,
+	}, nil
+}
+
+/* }}} */
+
+// Next : Read next chunk, false at end of stream or on error
+func (it *ResultIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if it.unary != nil {
+		r := it.unary
+		it.unary = nil
+		if err := r.Err(); err != nil {
+			it.finish(err)
+
+			return false
+		}
+
+		it.cur = r
+
+		return true
+	}
+
+	if it.body == nil {
+		it.finish(nil)
+
+		return false
+	}
+
+	kind, r, err := readRPCFrame(it.body)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("RPC stream closed without end")
+		}
+
+		it.finish(rpcContextError(it.ctx, err))
+
+		return false
+	}
+
+	if kind == rpcFrameEnd {
+		it.finish(r.Err())
+
+		return false
+	}
+
+	it.cur = r
+
+	return true
+}
+
+// Result : Current chunk
+func (it *ResultIterator) Result() *ResultMessage {
+	return it.cur
+}
+
+// Err : Error of stream (returned by handler or transport), valid after Next returns false
+func (it *ResultIterator) Err() error {
+	return it.err
+}
+
+// Close : Stop reading, server cancelled if stream not finished
+func (it *ResultIterator) Close() error {
+	it.finish(nil)
+
+	return nil
+}
+
+func (it *ResultIterator) finish(err error) {
+	if it.done {
+		return
+	}
+
+	it.done = true
+	it.cur = nil
+	it.err = err
+	if it.body != nil {
+		// Stream reset if not fully read, context of handler cancelled
+		it.body.Close()
+	}
+
+	it.release()
+
+	return
+}
+
+// readRPCFrame : Read one frame of stream, size limited by config "rpc.stream.max_frame"
+func readRPCFrame(r io.Reader) (byte, *ResultMessage, error) {
+	var header [5]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	max := Config().GetInt("rpc.stream.max_frame")
+	if max <= 0 {
+		max = DefaultRPCStreamFrame
+	}
+
+	if int64(size) > int64(max) {
+		return 0, nil, fmt.Errorf("RPC stream frame size %d exceeds limit %d", size, max)
+	}
+
+	buf := make([]byte, size)
+	_, err = io.ReadFull(r, buf)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ret := NewResultMessage(nil, false)
+	err = ret.Decode(buf)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], ret, nil
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,113 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file stream_test.go
+ * @package engine_test
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/drnp/deuterium/engine"
+)
+
+func TestStreamWithDeadline(t *testing.T) {
+	app := engine.NewApp("streamtest")
+	app.Silence()
+
+	engine.RegisterStreamHandler("count", func(msg *engine.UniformMessage, stream *engine.ResultStream) error {
+		for i := 0; i < 3; i++ {
+			err := stream.SendData(i, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	srv := engine.NewRPCServer()
+	srv.SetAddr("127.0.0.1:0")
+	srv.Startup(app.Logger())
+	defer srv.Shutdown()
+
+	host, port, err := net.SplitHostPort(srv.Addr())
+	if err != nil {
+		t.Fatalf("RPC server not listening : %s", err.Error())
+	}
+
+	p, _ := strconv.Atoi(port)
+	app.SetConfigs(map[string]interface{}{
+		"rpc.client.targets": []map[string]interface{}{
+			{"name": "streamtest", "addr": host, "port": p},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	it, err := engine.NewUniformMessage(nil, false).WithContext(ctx).Stream("streamtest", "count")
+	if err != nil {
+		t.Fatalf("Stream failed : %s", err.Error())
+	}
+
+	defer it.Close()
+
+	n := 0
+	for it.Next() {
+		var v int
+		err = it.Result().Unmarshal(&v)
+		if err != nil || v != n {
+			t.Fatalf("Chunk %d : got %d (%v)", n, v, err)
+		}
+
+		n++
+	}
+
+	if it.Err() != nil {
+		t.Fatalf("Stream ended with error : %s", it.Err().Error())
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", n)
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
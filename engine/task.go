@@ -67,6 +67,23 @@ func (th *_taskNsqConsumerHandler) HandleMessage(message *nsq.Message) error {
 		return err
 	}
 
+	err = acceptMessage(h, msg, TransportTask)
+	if err != nil {
+		Logger().Errorf("Task : Message <%s> rejected : %s", msg.ID, err.Error())
+
+		// Dead-lettered instead of lost, requeued if dead-letter topic unreachable
+		topic := fmt.Sprintf("%s%s", TaskDeadTopicPrefix, msg.Reciever)
+		err = App().nsq.Publish(topic, message.Body)
+		if err != nil {
+			Logger().Errorf("Task : Message <%s> dead-letter to <%s> failed : %s", msg.ID, topic, err.Error())
+			message.Requeue(-1)
+
+			return err
+		}
+
+		return nil
+	}
+
 	runHandler(h, msg)
 
 	return nil
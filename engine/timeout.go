@@ -0,0 +1,109 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file timeout.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	httpContextKey = "_engine.http.context"
+)
+
+// HTTPContext : Context of request, carries deadline of route timeout. Pass it to DB / Redis / RPC calls
+/* {{{ [HTTPContext] */
+func HTTPContext(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := ctx.UserValue(httpContextKey).(context.Context); ok {
+		return c
+	}
+
+	return ctx
+}
+
+/* }}} */
+
+// mwTimeout : Respond timeout envelope if handler not finished in time, handler keeps running with cancelled context
+/* {{{ [mwTimeout] */
+func mwTimeout(h fasthttp.RequestHandler, timeout time.Duration) fasthttp.RequestHandler {
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		c, cancel := context.WithTimeout(HTTPContext(ctx), timeout)
+		defer cancel()
+
+		ctx.SetUserValue(httpContextKey, c)
+		hook, _ := ctx.UserValue(httpTimeoutHookKey).(func(*fasthttp.Response))
+		done := make(chan struct{})
+		go func() {
+			h(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return
+		case <-c.Done():
+		}
+
+		err := ErrTimeout
+		if c.Err() == context.Canceled {
+			// Server shutting down
+			err = ErrUnavailable
+		}
+
+		Logger().Warnf("HTTP <%s %s> abandoned : %s", ctx.Method(), ctx.Path(), c.Err().Error())
+
+		// Response of ctx still owned by handler, build envelope aside
+		resp := new(fasthttp.RequestCtx)
+		ctx.Request.Header.CopyTo(&resp.Request.Header)
+		HTTPEnvelopeError(resp, err)
+		ctx.TimeoutErrorWithResponse(&resp.Response)
+		if hook != nil {
+			// Request not served by fasthttp server (batch)
+			hook(&resp.Response)
+		}
+
+		return
+	})
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
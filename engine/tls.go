@@ -0,0 +1,400 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file tls.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TLSReloadInterval : Interval of certificate file change detection
+	TLSReloadInterval = 30 * time.Second
+)
+
+// TLSConfig : Shared TLS settings of HTTP / RPC / metrics servers and RPC client
+type TLSConfig struct {
+	CertFile           string
+	KeyFile            string
+	CAFile             string
+	ClientAuth         bool
+	ServerName         string
+	MinVersion         string
+	CipherSuites       []string
+	InsecureSkipVerify bool
+}
+
+// TLSLoader : Certificates loaded from TLSConfig, reloaded on file change or SIGHUP
+type TLSLoader struct {
+	conf       *TLSConfig
+	cert       *tls.Certificate
+	pool       *x509.CertPool
+	minVersion uint16
+	ciphers    []uint16
+	modTime    time.Time
+	lock       sync.RWMutex
+}
+
+var (
+	tlsLoaders     []*TLSLoader
+	tlsLoadersLock sync.Mutex
+	tlsWatching    bool
+)
+
+// TLSConfigFromConfig : Read TLS settings from configuration by given key prefix, nil if not set.
+// Prefixes "http.server.tls", "rpc.server.tls", "metrics.tls" and "rpc.client.tls" read on startup if TLS not set by code
+/* {{{ [TLSConfigFromConfig] */
+func TLSConfigFromConfig(prefix string) *TLSConfig {
+	c := Config()
+	if c == nil || (c.GetString(prefix+".cert_file") == "" && c.GetString(prefix+".ca_file") == "" && !c.GetBool(prefix+".client_auth")) {
+		return nil
+	}
+
+	return &TLSConfig{
+		CertFile:           c.GetString(prefix + ".cert_file"),
+		KeyFile:            c.GetString(prefix + ".key_file"),
+		CAFile:             c.GetString(prefix + ".ca_file"),
+		ClientAuth:         c.GetBool(prefix + ".client_auth"),
+		ServerName:         c.GetString(prefix + ".server_name"),
+		MinVersion:         c.GetString(prefix + ".min_version"),
+		CipherSuites:       c.GetStringSlice(prefix + ".cipher_suites"),
+		InsecureSkipVerify: c.GetBool(prefix + ".insecure_skip_verify"),
+	}
+}
+
+/* }}} */
+
+// NewTLSLoader : Load certificates by given config and watch changes
+/* {{{ [NewTLSLoader] */
+func NewTLSLoader(conf *TLSConfig) (*TLSLoader, error) {
+	if conf == nil {
+		return nil, fmt.Errorf("Null TLS configuration")
+	}
+
+	if conf.ClientAuth && conf.CAFile == "" {
+		// Client certificates never verified without CA
+		return nil, fmt.Errorf("TLS client auth requires CA file")
+	}
+
+	l := &TLSLoader{
+		conf: conf,
+	}
+
+	v, err := parseTLSVersion(conf.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	l.minVersion = v
+	l.ciphers, err = parseTLSCiphers(conf.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsLoadersLock.Lock()
+	tlsLoaders = append(tlsLoaders, l)
+	if !tlsWatching {
+		tlsWatching = true
+		go watchTLSLoaders()
+	}
+
+	tlsLoadersLock.Unlock()
+
+	return l, nil
+}
+
+/* }}} */
+
+// Close : Stop watching certificate files of loader, configs generated before keep the last loaded certificates
+/* {{{ [TLSLoader::Close] */
+func (l *TLSLoader) Close() {
+	tlsLoadersLock.Lock()
+	for i, loader := range tlsLoaders {
+		if loader == l {
+			tlsLoaders = append(tlsLoaders[:i], tlsLoaders[i+1:]...)
+
+			break
+		}
+	}
+
+	tlsLoadersLock.Unlock()
+
+	return
+}
+
+/* }}} */
+
+// Reload : Read certificate, key and CA bundle again
+/* {{{ [TLSLoader::Reload] */
+func (l *TLSLoader) Reload() error {
+	var (
+		cert *tls.Certificate
+		pool *x509.CertPool
+	)
+
+	if l.conf.CertFile != "" && l.conf.KeyFile != "" {
+		c, err := tls.LoadX509KeyPair(l.conf.CertFile, l.conf.KeyFile)
+		if err != nil {
+			return err
+		}
+
+		cert = &c
+	}
+
+	if l.conf.CAFile != "" {
+		pem, err := ioutil.ReadFile(l.conf.CAFile)
+		if err != nil {
+			return err
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("No certificate found in CA bundle <%s>", l.conf.CAFile)
+		}
+	}
+
+	l.lock.Lock()
+	l.cert = cert
+	l.pool = pool
+	l.modTime = l.lastModified()
+	l.lock.Unlock()
+
+	return nil
+}
+
+/* }}} */
+
+// ServerConfig : Generate server side tls.Config, certificates are picked up per handshake
+/* {{{ [TLSLoader::ServerConfig] */
+func (l *TLSLoader) ServerConfig(nextProtos ...string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:   l.minVersion,
+		CipherSuites: l.ciphers,
+		NextProtos:   nextProtos,
+	}
+
+	if l.conf.CAFile != "" {
+		if l.conf.ClientAuth {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		} else {
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		l.lock.RLock()
+		defer l.lock.RUnlock()
+
+		return l.cert, nil
+	}
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		l.lock.RLock()
+		defer l.lock.RUnlock()
+
+		if l.cert == nil {
+			return nil, fmt.Errorf("No server certificate loaded")
+		}
+
+		// Server may append protocols (h2) to config after generated
+		c := cfg.Clone()
+		c.GetCertificate = nil
+		c.GetConfigForClient = nil
+		c.Certificates = []tls.Certificate{*l.cert}
+		c.ClientCAs = l.pool
+
+		return c, nil
+	}
+
+	return cfg
+}
+
+/* }}} */
+
+// ClientConfig : Generate client side tls.Config, CA bundle taken as loaded now, so generate one per connection to follow reloads
+/* {{{ [TLSLoader::ClientConfig] */
+func (l *TLSLoader) ClientConfig() *tls.Config {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	cfg := &tls.Config{
+		MinVersion:         l.minVersion,
+		CipherSuites:       l.ciphers,
+		RootCAs:            l.pool,
+		ServerName:         l.conf.ServerName,
+		InsecureSkipVerify: l.conf.InsecureSkipVerify,
+	}
+
+	if l.cert != nil {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			l.lock.RLock()
+			defer l.lock.RUnlock()
+
+			return l.cert, nil
+		}
+	}
+
+	return cfg
+}
+
+/* }}} */
+
+// lastModified : Latest modification time of certificate files
+func (l *TLSLoader) lastModified() time.Time {
+	var last time.Time
+	for _, f := range []string{l.conf.CertFile, l.conf.KeyFile, l.conf.CAFile} {
+		if f == "" {
+			continue
+		}
+
+		st, err := os.Stat(f)
+		if err == nil && st.ModTime().After(last) {
+			last = st.ModTime()
+		}
+	}
+
+	return last
+}
+
+// ReloadTLS : Reload all TLS loaders, triggered by SIGHUP
+/* {{{ [ReloadTLS] */
+func ReloadTLS() {
+	tlsLoadersLock.Lock()
+	loaders := append([]*TLSLoader{}, tlsLoaders...)
+	tlsLoadersLock.Unlock()
+
+	for _, l := range loaders {
+		err := l.Reload()
+		if err != nil {
+			Logger().Errorf("TLS certificate <%s> reload failed : %s", l.conf.CertFile, err.Error())
+		} else {
+			Logger().Infof("TLS certificate <%s> reloaded", l.conf.CertFile)
+		}
+	}
+
+	return
+}
+
+/* }}} */
+
+// watchTLSLoaders : Reload loaders whose files changed
+func watchTLSLoaders() {
+	ticker := time.NewTicker(TLSReloadInterval)
+	for range ticker.C {
+		tlsLoadersLock.Lock()
+		loaders := append([]*TLSLoader{}, tlsLoaders...)
+		tlsLoadersLock.Unlock()
+
+		for _, l := range loaders {
+			l.lock.RLock()
+			changed := l.lastModified().After(l.modTime)
+			l.lock.RUnlock()
+			if !changed {
+				continue
+			}
+
+			err := l.Reload()
+			if err != nil {
+				Logger().Errorf("TLS certificate <%s> reload failed : %s", l.conf.CertFile, err.Error())
+			} else {
+				Logger().Infof("TLS certificate <%s> changed, reloaded", l.conf.CertFile)
+			}
+		}
+	}
+}
+
+/* {{{ [Helpers] */
+
+func parseTLSVersion(v string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(v), "tls") {
+	case "":
+		return tls.VersionTLS12, nil
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("Unknown TLS version <%s>", v)
+}
+
+func parseTLSCiphers(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, c := range tls.CipherSuites() {
+		known[c.Name] = c.ID
+	}
+
+	for _, c := range tls.InsecureCipherSuites() {
+		known[c.Name] = c.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("Unknown cipher suite <%s>", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,494 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) [year] [fullname]
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/**
+ * @file upload.go
+ * @package engine
+ * author Dr.NP <conan.np@gmail.com>
+ * @since 10/18/2026
+ */
+
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// HTTPUploadSniffLength : Bytes used for MIME sniffing
+	HTTPUploadSniffLength = 512
+	// HTTPUploadFormOverhead : Body allowed besides files (boundaries, headers, other fields)
+	HTTPUploadFormOverhead = 64 * 1024
+	// HTTPUploadBufferSize : Body of upload route buffered before streamed
+	HTTPUploadBufferSize = 64 * 1024
+
+	httpUploadConfigKey = "_engine.http.upload_config"
+	httpUploadFilesKey  = "_engine.http.upload_files"
+)
+
+// HTTPUploadConfig : Upload limits of route.
+// Multipart body streamed into temporary files before handler called, limits checked while reading.
+// Whole body bounded by MaxBodySize of route, or MaxFileSize * MaxFiles if both set
+type HTTPUploadConfig struct {
+	MaxFileSize       int64
+	MaxFiles          int
+	AllowedExtensions []string
+	AllowedTypes      []string
+	TempDir           string
+}
+
+// HTTPUploadFile : Descriptor of uploaded file, spooled in temporary directory until request ends
+type HTTPUploadFile struct {
+	Field       string
+	Name        string
+	Extension   string
+	Size        int64
+	ContentType string
+	Hash        string
+	Path        string
+}
+
+// httpUploads : Spooled files and form values of request
+type httpUploads struct {
+	files  []*HTTPUploadFile
+	values map[string][]string
+	err    error
+}
+
+// uploadLimitReader : Body reader failed once more than limit read
+type uploadLimitReader struct {
+	r        io.Reader
+	left     int64
+	exceeded bool
+}
+
+// Open : Open spooled file for reading
+/* {{{ [HTTPUploadFile::Open] */
+func (f *HTTPUploadFile) Open() (*os.File, error) {
+	return os.Open(f.Path)
+}
+
+/* }}} */
+
+// SaveAs : Move spooled file to given path, the file will not be cleaned up after request
+/* {{{ [HTTPUploadFile::SaveAs] */
+func (f *HTTPUploadFile) SaveAs(path string) error {
+	err := os.Rename(f.Path, path)
+	if err != nil {
+		// Cross device
+		src, err := os.Open(f.Path)
+		if err != nil {
+			return err
+		}
+
+		defer src.Close()
+		dst, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+
+		defer dst.Close()
+		_, err = io.Copy(dst, src)
+		if err != nil {
+			return err
+		}
+
+		os.Remove(f.Path)
+	}
+
+	f.Path = path
+
+	return nil
+}
+
+/* }}} */
+
+// mwRequestBody : Bound body streamed by fasthttp (larger than buffered size or chunked), outermost of route.
+// Multipart body of upload route spooled into files here, others buffered up to limit. Spooled files cleaned up when request ends
+func mwRequestBody(h fasthttp.RequestHandler, conf *HTTPUploadConfig, limit int) fasthttp.RequestHandler {
+	return fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			uploads, ok := ctx.UserValue(httpUploadFilesKey).(*httpUploads)
+			if ok {
+				for _, f := range uploads.files {
+					os.Remove(f.Path)
+				}
+			}
+
+			if ctx.Request.IsBodyStream() {
+				// Rest of body unread
+				ctx.SetConnectionClose()
+			}
+		}()
+
+		// Body in memory already for in-process requests (batch)
+		if limit > 0 && (ctx.Request.Header.ContentLength() > limit || (!ctx.Request.IsBodyStream() && len(ctx.Request.Body()) > limit)) {
+			HTTPEnvelopeError(ctx, Errorf(ErrorCodeBadRequest, http.StatusRequestEntityTooLarge, "Request body too large, at most %d bytes", limit))
+
+			return
+		}
+
+		if conf != nil {
+			ctx.SetUserValue(httpUploadConfigKey, conf)
+			if len(ctx.Request.Header.MultipartFormBoundary()) > 0 {
+				// Parsed before handler (maybe run in goroutine by timeout), connection read here only
+				uploads := httpUploadsOf(ctx, limit)
+				if uploads.err == nil {
+					drainRequestBody(ctx)
+				}
+
+				h(ctx)
+
+				return
+			}
+		}
+
+		if ctx.Request.IsBodyStream() {
+			lr := newUploadLimitReader(ctx.RequestBodyStream(), limit)
+			body, err := ioutil.ReadAll(lr)
+			if lr.exceeded {
+				HTTPEnvelopeError(ctx, Errorf(ErrorCodeBadRequest, http.StatusRequestEntityTooLarge, "Request body too large, at most %d bytes", limit))
+
+				return
+			} else if err != nil {
+				HTTPEnvelopeError(ctx, NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Read request body failed").Wrap(err))
+
+				return
+			}
+
+			ctx.Request.SetBody(body)
+		}
+
+		h(ctx)
+
+		return
+	})
+}
+
+// HTTPUploadFiles : Files of multipart request, spooled by upload config of route
+/* {{{ [HTTPUploadFiles] */
+func HTTPUploadFiles(ctx *fasthttp.RequestCtx) ([]*HTTPUploadFile, error) {
+	uploads := httpUploadsOf(ctx, 0)
+	if uploads.err != nil {
+		return nil, uploads.err
+	}
+
+	return uploads.files, nil
+}
+
+/* }}} */
+
+// httpMultipartValues : Values (not files) of multipart request
+func httpMultipartValues(ctx *fasthttp.RequestCtx) (map[string][]string, error) {
+	uploads := httpUploadsOf(ctx, 0)
+
+	return uploads.values, uploads.err
+}
+
+// httpUploadsOf : Parse multipart body once, files written into temporary directory while read
+/* {{{ [httpUploadsOf] */
+func httpUploadsOf(ctx *fasthttp.RequestCtx, limit int) *httpUploads {
+	uploads, ok := ctx.UserValue(httpUploadFilesKey).(*httpUploads)
+	if ok {
+		return uploads
+	}
+
+	// Stored before spooling, so partial files will be removed on failure
+	uploads = &httpUploads{
+		files:  make([]*HTTPUploadFile, 0),
+		values: make(map[string][]string),
+	}
+	ctx.SetUserValue(httpUploadFilesKey, uploads)
+
+	conf, _ := ctx.UserValue(httpUploadConfigKey).(*HTTPUploadConfig)
+	if conf == nil {
+		conf = &HTTPUploadConfig{}
+	}
+
+	boundary := string(ctx.Request.Header.MultipartFormBoundary())
+	if boundary == "" {
+		uploads.err = NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Invalid multipart form").Wrap(fasthttp.ErrNoMultipartForm)
+
+		return uploads
+	}
+
+	var body io.Reader
+	if ctx.Request.IsBodyStream() {
+		body = ctx.RequestBodyStream()
+	} else {
+		body = bytes.NewReader(ctx.Request.Body())
+	}
+
+	lr := newUploadLimitReader(body, limit)
+	uploads.err = uploads.parse(multipart.NewReader(lr, boundary), conf)
+	if lr.exceeded {
+		uploads.err = Errorf(ErrorCodeBadRequest, http.StatusRequestEntityTooLarge, "Request body too large, at most %d bytes", limit)
+	}
+
+	return uploads
+}
+
+/* }}} */
+
+// parse : Read parts of multipart body in order
+/* {{{ [httpUploads::parse] */
+func (uploads *httpUploads) parse(mr *multipart.Reader, conf *HTTPUploadConfig) error {
+	valuesSize := 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Invalid multipart form").Wrap(err)
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			// Value, bounded by form overhead
+			v, err := ioutil.ReadAll(io.LimitReader(part, int64(HTTPUploadFormOverhead-valuesSize+1)))
+			if err != nil {
+				return NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Invalid multipart form").Wrap(err)
+			}
+
+			valuesSize += len(v)
+			if valuesSize > HTTPUploadFormOverhead {
+				return Errorf(ErrorCodeBadRequest, http.StatusRequestEntityTooLarge, "Form values too large, at most %d bytes", HTTPUploadFormOverhead)
+			}
+
+			uploads.values[field] = append(uploads.values[field], string(v))
+
+			continue
+		}
+
+		if conf.MaxFiles > 0 && len(uploads.files) >= conf.MaxFiles {
+			return Errorf(ErrorCodeBadRequest, http.StatusRequestEntityTooLarge, "Too many files, at most %d", conf.MaxFiles)
+		}
+
+		f, err := spoolUploadFile(field, part, conf)
+		if f != nil {
+			uploads.files = append(uploads.files, f)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/* }}} */
+
+// HTTPUploadFilesOf : Get uploaded files of given field
+/* {{{ [HTTPUploadFilesOf] */
+func HTTPUploadFilesOf(ctx *fasthttp.RequestCtx, field string) ([]*HTTPUploadFile, error) {
+	files, err := HTTPUploadFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*HTTPUploadFile
+	for _, f := range files {
+		if f.Field == field {
+			ret = append(ret, f)
+		}
+	}
+
+	return ret, nil
+}
+
+/* }}} */
+
+// spoolUploadFile : Write file part into temporary directory, calculate hash and sniff MIME type
+/* {{{ [spoolUploadFile] */
+func spoolUploadFile(field string, part *multipart.Part, conf *HTTPUploadConfig) (*HTTPUploadFile, error) {
+	name := filepath.Base(part.FileName())
+	f := &HTTPUploadFile{
+		Field:     field,
+		Name:      name,
+		Extension: strings.ToLower(strings.TrimPrefix(filepath.Ext(name), ".")),
+	}
+
+	if len(conf.AllowedExtensions) > 0 && !inStringList(f.Extension, conf.AllowedExtensions) {
+		return nil, Errorf(ErrorCodeBadRequest, http.StatusUnsupportedMediaType, "File extension <%s> not allowed", f.Extension)
+	}
+
+	dir := conf.TempDir
+	if dir == "" && Config() != nil {
+		dir = Config().GetString("http.upload.temp_dir")
+	}
+
+	dst, err := ioutil.TempFile(dir, "upload-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer dst.Close()
+	f.Path = dst.Name()
+
+	var src io.Reader = part
+	if conf.MaxFileSize > 0 {
+		// One byte more tells oversize
+		src = io.LimitReader(part, conf.MaxFileSize+1)
+	}
+
+	head := make([]byte, HTTPUploadSniffLength)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return f, NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Read uploaded file failed").Wrap(err)
+	}
+
+	f.ContentType = http.DetectContentType(head[:n])
+	if len(conf.AllowedTypes) > 0 && !matchContentType(f.ContentType, conf.AllowedTypes) {
+		return f, Errorf(ErrorCodeBadRequest, http.StatusUnsupportedMediaType, "File type <%s> not allowed", f.ContentType)
+	}
+
+	hash := sha256.New()
+	w := io.MultiWriter(dst, hash)
+	w.Write(head[:n])
+	size, err := io.Copy(w, src)
+	if err != nil {
+		return f, NewError(ErrorCodeBadRequest, http.StatusBadRequest, "Read uploaded file failed").Wrap(err)
+	}
+
+	f.Size = size + int64(n)
+	if conf.MaxFileSize > 0 && f.Size > conf.MaxFileSize {
+		return f, Errorf(ErrorCodeBadRequest, http.StatusRequestEntityTooLarge, "File <%s> too large, at most %d bytes", name, conf.MaxFileSize)
+	}
+
+	f.Hash = hex.EncodeToString(hash.Sum(nil))
+
+	return f, nil
+}
+
+/* }}} */
+
+/* {{{ [Helpers] */
+
+// newUploadLimitReader : Reader of body bounded by limit, unlimited if 0
+func newUploadLimitReader(r io.Reader, limit int) *uploadLimitReader {
+	lr := &uploadLimitReader{r: r, left: int64(limit)}
+	if limit <= 0 {
+		lr.left = -1
+	}
+
+	return lr
+}
+
+// Read : Read body, failed once limit (negative for unlimited) exceeded
+func (l *uploadLimitReader) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return l.r.Read(p)
+	}
+
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+
+	n, err := l.r.Read(p)
+	if int64(n) > l.left {
+		l.exceeded = true
+
+		return 0, fasthttp.ErrBodyTooLarge
+	}
+
+	l.left -= int64(n)
+
+	return n, err
+}
+
+// drainRequestBody : Consume epilogue of streamed body, so connection kept alive
+func drainRequestBody(ctx *fasthttp.RequestCtx) {
+	if !ctx.Request.IsBodyStream() {
+		return
+	}
+
+	n, err := io.Copy(ioutil.Discard, io.LimitReader(ctx.RequestBodyStream(), HTTPUploadFormOverhead+1))
+	if err == nil && n <= HTTPUploadFormOverhead {
+		// Whole body read, stream released
+		ctx.Request.SetBody(nil)
+	}
+
+	return
+}
+
+// bodyLimit : Request body limit derived from file limits, 0 for server default
+func (conf *HTTPUploadConfig) bodyLimit() int {
+	if conf.MaxFileSize <= 0 || conf.MaxFiles <= 0 {
+		return 0
+	}
+
+	limit := conf.MaxFileSize*int64(conf.MaxFiles) + HTTPUploadFormOverhead
+	if limit > int64(^uint(0)>>1) {
+		return 0
+	}
+
+	return int(limit)
+}
+
+func inStringList(s string, list []string) bool {
+	for _, v := range list {
+		if strings.ToLower(strings.TrimPrefix(v, ".")) == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchContentType(ct string, list []string) bool {
+	ct = strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	for _, v := range list {
+		v = strings.ToLower(v)
+		if strings.HasSuffix(v, "/*") {
+			if strings.HasPrefix(ct, strings.TrimSuffix(v, "*")) {
+				return true
+			}
+		} else if v == ct {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -3,13 +3,12 @@ module github.com/drnp/deuterium
 go 1.14
 
 require (
-	github.com/andybalholm/brotli v1.0.1 // indirect
 	github.com/eclipse/paho.mqtt.golang v1.3.0
 	github.com/fasthttp/router v1.3.3
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-redis/redis/v8 v8.4.2
-	github.com/golang/snappy v0.0.2
+	github.com/golang/snappy v0.0.3
 	github.com/google/uuid v1.1.2
-	github.com/klauspost/compress v1.11.3 // indirect
 	github.com/lib/pq v1.9.0 // indirect
 	github.com/magiconair/properties v1.8.4 // indirect
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible // indirect
@@ -29,12 +28,9 @@ require (
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/spf13/viper v1.7.1
 	github.com/upper/db/v4 v4.0.1
-	github.com/valyala/fasthttp v1.18.0
+	github.com/valyala/fasthttp v1.26.0
 	github.com/vmihailenco/msgpack v4.0.4+incompatible
-	golang.org/x/crypto v0.0.0-20201208171446-5f87f3452ae9 // indirect
-	golang.org/x/net v0.0.0-20201209123823-ac852fbbde11
-	golang.org/x/sys v0.0.0-20201207223542-d4d67f95c62d // indirect
-	golang.org/x/text v0.3.4 // indirect
+	golang.org/x/net v0.0.0-20210510120150-4163338589ed
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.25.0 // indirect
 	gopkg.in/ini.v1 v1.62.0 // indirect